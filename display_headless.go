@@ -0,0 +1,18 @@
+//go:build !sdl
+// +build !sdl
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/ypujante/ray-tracing/tracer"
+)
+
+// sdlEnabled is true when the program is built with SDL support (go build -tags sdl)
+const sdlEnabled = false
+
+// renderWindow is not available without SDL support
+func renderWindow(scene *tracer.Scene, options Options) error {
+	return fmt.Errorf("cannot open a window: built without SDL support (build with -tags sdl or use -headless)")
+}
@@ -0,0 +1,134 @@
+//go:build sdl
+// +build sdl
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"unsafe"
+
+	"github.com/veandco/go-sdl2/sdl"
+
+	"github.com/ypujante/ray-tracing/tracer"
+)
+
+// sdlEnabled is true when the program is built with SDL support (go build -tags sdl)
+const sdlEnabled = true
+
+// display will update the screen with the pixels provided (a snapshot of the frame buffer, see FrameBuffer.Snapshot)
+func display(window *sdl.Window, screen *sdl.Surface, scene *tracer.Scene, pixels tracer.Pixels) {
+	// create an image from the pixels generated
+	image, err := sdl.CreateRGBSurfaceFrom(unsafe.Pointer(&pixels[0]), int32(scene.Width), int32(scene.Height), 32, scene.Width*int(unsafe.Sizeof(pixels[0])), 0, 0, 0, 0)
+	if err != nil {
+		panic(err)
+	}
+	defer image.Free()
+	// copy it into the screen
+	err = image.Blit(nil, screen, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	// update the surface to show it
+	err = window.UpdateSurface()
+	if err != nil {
+		panic(err)
+	}
+}
+
+// renderWindow sets up the Window/Screen and renders the scene. As the scene gets rendered the screen gets
+// refreshed regularly to show progress. When the image is fully rendered, it saves it to a file (if the output
+// option is set). Returns when the window is closed (which stops the rendering if still in progress).
+func renderWindow(scene *tracer.Scene, options Options) error {
+	// initializes SDL
+	if err := sdl.Init(sdl.INIT_VIDEO); err != nil {
+		return err
+	}
+	defer sdl.Quit()
+
+	// create (and show) window
+	window, err := sdl.CreateWindow("Ray Tracing", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, int32(options.Width), int32(options.Height), sdl.WINDOW_SHOWN)
+	if err != nil {
+		return err
+	}
+	defer window.Destroy()
+
+	// retrieves the screen
+	screen, err := window.GetSurface()
+	if err != nil {
+		return err
+	}
+
+	// clear the screen (otherwise there is garbage...)
+	err = screen.FillRect(&sdl.Rect{W: int32(options.Width), H: int32(options.Height)}, 0x00000000)
+	if err != nil {
+		return err
+	}
+
+	// closing the window stops the rendering
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fb, done := scene.RenderContext(ctx, options.CPU, printProgress)
+
+	// update the surface to show it
+	err = window.UpdateSurface()
+	if err != nil {
+		return err
+	}
+
+	updateDisplay := true
+	var saveErr error
+
+	// the snapshot of the frame buffer being displayed (reused between frames)
+	var pixels tracer.Pixels
+	displayedVersion := ^uint64(0)
+
+	// poll for quit event
+	for running := true; running; {
+		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+			switch event.(type) {
+			case *sdl.QuitEvent:
+				running = false
+			}
+		}
+
+		// wait a few between iterations
+		sdl.Delay(16)
+
+		if updateDisplay {
+
+			// check (non blocking thanks to select) that the image is completely rendered (before taking the
+			// snapshot so that the last one displayed and saved is complete)
+			var err error
+			completed := false
+			select {
+			case err = <-done:
+				completed = true
+			default:
+				break
+			}
+
+			// only refresh the screen when the frame buffer has changed
+			if fb.Version() != displayedVersion || completed {
+				pixels, displayedVersion = fb.Snapshot(pixels)
+				display(window, screen, scene, pixels)
+			}
+
+			if completed {
+				updateDisplay = false
+				if err == nil {
+					fmt.Println("Render complete.")
+					saveErr = saveRenderedImage(fb, options)
+					if saveErr != nil {
+						fmt.Println(saveErr)
+					}
+				}
+			}
+
+		}
+	}
+
+	return saveErr
+}
@@ -2,8 +2,7 @@
 package main
 
 import (
-	"github.com/veandco/go-sdl2/sdl"
-	"unsafe"
+	"context"
 	"runtime"
 	"fmt"
 	"math/rand"
@@ -13,7 +12,10 @@ import (
 	"image"
 	clr "image/color"
 	"os"
-	"image/png"
+	"os/signal"
+	"math"
+
+	"github.com/ypujante/ray-tracing/tracer"
 )
 
 // RaysPerPixelList is used on the command line (flag) to define the number of rays per pixel per phase (hence a list)
@@ -41,152 +43,44 @@ type Options struct {
 	Height       int
 	RaysPerPixel RaysPerPixelList
 	Output       string
+	Background   string
+	Model        string
+	GLTF         string
+	Scene        string
+	SceneName    string
+	ListScenes   bool
+	EnvRotation  float64
+	EnvIntensity float64
 	Seed         int64
+	Headless     bool
 	CPU          int
+	BVH          tracer.SplitStrategy
+	TileSize     int
+	TileOrder    tracer.TileOrder
+	Sampler      tracer.SamplerType
+	EXR          tracer.EXROptions
+	ToneMap      string
+	Exposure     float64
+	White        float64
+	Transfer     string
+	Filter       string
+	FilterRadius float64
+
+	// OutputTransform is parsed from Transfer
+	OutputTransform tracer.OutputTransform
 }
 
-// display will update the screen with the pixels provided
-// note that there is no synchronization required on the array of pixels since it is an array of 32 bits integers
-// that only gets updated to a final value by 1 goroutine at a time
-func display(window *sdl.Window, screen *sdl.Surface, scene *Scene, pixels Pixels) {
-	// create an image from the pixels generated
-	image, err := sdl.CreateRGBSurfaceFrom(unsafe.Pointer(&pixels[0]), int32(scene.width), int32(scene.height), 32, scene.width*int(unsafe.Sizeof(pixels[0])), 0, 0, 0, 0)
-	if err != nil {
-		panic(err)
-	}
-	defer image.Free()
-	// copy it into the screen
-	err = image.Blit(nil, screen, nil)
-	if err != nil {
-		panic(err)
-	}
-
-	// update the surface to show it
-	err = window.UpdateSurface()
-	if err != nil {
-		panic(err)
-	}
-}
-
-// buildWorld is the end result chapter 7
-func buildWorldChapter7(width, height int) (Camera, HitableList) {
-	lookFrom := Point3{0, 0.0, 3.0}
-	lookAt := Point3{Z: -1.0}
-	aperture := 0.0
-	distToFocus := 1.0
-	camera := NewCamera(lookFrom, lookAt, Vec3{Y: 1.0}, 20, float64(width)/float64(height), aperture, distToFocus)
-
-	world := HitableList{
-		Sphere{center: Point3{Z: -1.0}, radius: 0.5, material: Lambertian{Color{R: 1.0}}},
-		Sphere{center: Point3{Y: -100.5, Z: -1.0}, radius: 100, material: Lambertian{Color{G: 1.0}}},
-	}
-
-	return camera, world
-}
-
-// buildWorldMetalSpheres is the end result chapter 8
-func buildWorldMetalSpheres(width, height int) (Camera, HitableList) {
-	lookFrom := Point3{0, 0.0, 3.0}
-	lookAt := Point3{Z: -1.0}
-	aperture := 0.0
-	distToFocus := 1.0
-	camera := NewCamera(lookFrom, lookAt, Vec3{Y: 1.0}, 20, float64(width)/float64(height), aperture, distToFocus)
-
-	world := HitableList{
-		Sphere{center: Point3{Z: -1.0}, radius: 0.5, material: Lambertian{Color{R: 0.8, G: 0.3, B: 0.3}}},
-		Sphere{center: Point3{Y: -100.5, Z: -1.0}, radius: 100, material: Lambertian{Color{R: 0.8, G: 0.8}}},
-		Sphere{center: Point3{X: 1.0, Y: 0, Z: -1.0}, radius: 0.5, material: Metal{Color{R: 0.8, G: 0.6, B: 0.2}, 1.0}},
-		Sphere{center: Point3{X: -1.0, Y: 0, Z: -1.0}, radius: 0.5, material: Metal{Color{R: 0.8, G: 0.8, B: 0.8}, 0.3}},
-	}
-
-	return camera, world
-}
-
-// buildWorldDielectrics is the end result chapter 10
-func buildWorldDielectrics(width, height int) (Camera, HitableList) {
-
-	lookFrom := Point3{-2.0, 2.0, 1.0}
-	lookAt := Point3{Z: -1.0}
-	aperture := 0.0
-	distToFocus := 1.0
-	camera := NewCamera(lookFrom, lookAt, Vec3{Y: 1.0}, 20, float64(width)/float64(height), aperture, distToFocus)
-
-	world := HitableList{
-		Sphere{center: Point3{Z: -1.0}, radius: 0.5, material: Lambertian{Color{R: 0.1, G: 0.2, B: 0.5}}},
-		Sphere{center: Point3{Y: -100.5, Z: -1.0}, radius: 100, material: Lambertian{Color{R: 0.8, G: 0.8}}},
-		Sphere{center: Point3{X: 1.0, Y: 0, Z: -1.0}, radius: 0.5, material: Metal{Color{R: 0.8, G: 0.6, B: 0.2}, 1.0}},
-		Sphere{center: Point3{X: -1.0, Y: 0, Z: -1.0}, radius: 0.5, material: Dielectric{1.5}},
-		Sphere{center: Point3{X: -1.0, Y: 0, Z: -1.0}, radius: -0.45, material: Dielectric{1.5}},
-	}
-
-	return camera, world
-}
-
-// buildWorldDielectrics is the end result book
-func buildWorldOneWeekend(width, height int) (Camera, HitableList) {
-	world := []Hitable{}
-
-	maxSpheres := 500
-	world = append(world, Sphere{center: Point3{Y: -1000.0}, radius: 1000, material: Lambertian{Color{R: 0.5, G: 0.5, B: 0.5}}})
-
-	for a := -11; a < 11 && len(world) < maxSpheres; a++ {
-		for b := -11; b < 11 && len(world) < maxSpheres; b++ {
-			chooseMaterial := rand.Float64()
-			center := Point3{float64(a) + 0.9*rand.Float64(), 0.2, float64(b) + 0.9*rand.Float64()}
-
-			if center.Sub(Point3{4.0, 0.2, 0}).Length() > 0.9 {
-				switch {
-				case chooseMaterial < 0.8: // diffuse
-					world = append(world,
-						Sphere{
-							center:   center,
-							radius:   0.2,
-							material: Lambertian{Color{R: rand.Float64() * rand.Float64(), G: rand.Float64() * rand.Float64(), B: rand.Float64() * rand.Float64()}}})
-				case chooseMaterial < 0.95: // metal
-					world = append(world,
-						Sphere{
-							center:   center,
-							radius:   0.2,
-							material: Metal{Color{R: 0.5 * (1 + rand.Float64()), G: 0.5 * (1 + rand.Float64()), B: 0.5 * (1 + rand.Float64())}, 0.5 * rand.Float64()}})
-				default:
-					world = append(world,
-						Sphere{
-							center:   center,
-							radius:   0.2,
-							material: Dielectric{1.5}})
-
-				}
-			}
+// saveImage saves the image (if requested) to a file whose format depends on the extension: the linear (unclamped)
+// colors of the accumulation buffer for HDR formats (exr, pfm or hdr), the pixels in png format otherwise
+func saveImage(fb *tracer.FrameBuffer, options Options) (error, bool) {
+	if options.Output != "" {
+		if tracer.IsHDRImageFile(options.Output) {
+			return tracer.SaveHDRImage(options.Output, fb.Accumulation().Image(), options.EXR), true
 		}
-	}
 
-	world = append(world,
-		Sphere{
-			center:   Point3{0, 1, 0},
-			radius:   1.0,
-			material: Dielectric{1.5}},
-		Sphere{
-			center:   Point3{-4, 1, 0},
-			radius:   1.0,
-			material: Lambertian{Color{0.4, 0.2, 0.1}}},
-		Sphere{
-			center:   Point3{4, 1, 0},
-			radius:   1.0,
-			material: Metal{Color{0.7, 0.6, 0.5}, 0}})
-
-	lookFrom := Point3{13, 2, 3}
-	lookAt := Point3{}
-	aperture := 0.1
-	distToFocus := 10.0
-	camera := NewCamera(lookFrom, lookAt, Vec3{Y: 1.0}, 20, float64(width)/float64(height), aperture, distToFocus)
-
-	return camera, world
-}
+		pixels, _ := fb.Snapshot(nil)
 
-// saveImage saves the image (if requested) to a file in png format
-func saveImage(pixels Pixels, options Options) (error, bool) {
-	if options.Output != "" {
-		f, err := os.OpenFile(options.Output, os.O_RDWR|os.O_CREATE, 0755)
+		f, err := os.OpenFile(options.Output, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
 		if err != nil {
 			return err, true
 		}
@@ -207,7 +101,7 @@ func saveImage(pixels Pixels, options Options) (error, bool) {
 			}
 		}
 
-		if err := png.Encode(f, img); err != nil {
+		if err := tracer.EncodePNG(f, img, options.OutputTransform); err != nil {
 			f.Close()
 			return err, true
 		}
@@ -223,101 +117,178 @@ func saveImage(pixels Pixels, options Options) (error, bool) {
 
 }
 
-// main parses the options, set up the Window/Screen, builds the world and renders the scene.
-// As the scene gets rendered the screen gets refreshed regularly to show progress. When the image is fully
-// rendered, it saves it to a file (if the output option is set)
+// main parses the options, builds the world and renders the scene. The scene is either rendered in a window
+// (when built with SDL support, -tags sdl) where the screen gets refreshed regularly to show progress, or headless
+// (-headless) where the progress is printed. When the image is fully rendered, it saves it to a file (if the
+// output option is set). The exit status is 0 on success and 1 on error.
 func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// registerFlags defines the command line flags which set the options
+func registerFlags(fs *flag.FlagSet, options *Options) {
+	fs.IntVar(&options.Width, "w", 800, "width in pixel")
+	fs.IntVar(&options.Height, "h", 400, "height in pixel")
+	fs.IntVar(&options.CPU, "cpu", runtime.NumCPU(), "number of CPU to use (default to number of CPU available)")
+	fs.Int64Var(&options.Seed, "seed", 2017, "seed for random number generator")
+	fs.Var(&options.RaysPerPixel, "r", "comma separated list (or multiple) rays per pixel")
+	fs.StringVar(&options.Output, "o", "", "path to file for saving: png, or exr, pfm and hdr for linear HDR output (do not save if not defined)")
+	fs.Var(&options.EXR.PixelType, "exr-type", "pixel type of the exr output (half or float)")
+	fs.Var(&options.EXR.Compression, "exr-compression", "compression of the exr output (zip or none)")
+	fs.StringVar(&options.ToneMap, "tonemap", "clamp", "tone mapping operator applied for display and png output: "+strings.Join(tracer.ToneMapperNames, ", "))
+	fs.Float64Var(&options.Exposure, "exposure", 0, "exposure (in stops) applied before tone mapping")
+	fs.StringVar(&options.Transfer, "transfer", "srgb", "output transform applied for display and png output (embedded in the png): srgb, linear, gamma (2.2) or gamma:g")
+	fs.StringVar(&options.Filter, "filter", "box", "pixel reconstruction filter: "+strings.Join(tracer.FilterNames, ", "))
+	fs.Float64Var(&options.FilterRadius, "filter-radius", 0, "radius (in pixels) of the reconstruction filter (default to the one of the filter)")
+	fs.Float64Var(&options.White, "white", 0, "white point of the reinhard-extended and hable tone mapping operators (default to the one of the operator)")
+	fs.BoolVar(&options.Headless, "headless", !sdlEnabled, "render without opening a window (printing progress) and exit when done")
+	fs.StringVar(&options.SceneName, "scene-name", defaultSceneName, "name of the built-in scene to render (see -list-scenes)")
+	fs.BoolVar(&options.ListScenes, "list-scenes", false, "list the built-in scenes and exit")
+	fs.StringVar(&options.Scene, "scene", "", "path to a scene file (json or yaml) to render")
+	fs.StringVar(&options.Model, "model", "", "path to a model (obj, ply, stl, gltf or glb) to render (framed by the camera on a ground plane)")
+	fs.StringVar(&options.GLTF, "gltf", "", "path to a glTF scene (gltf or glb) to render (using the first perspective camera of the file)")
+	fs.StringVar(&options.Background, "background", "", "background: sky, black, white, color:r,g,b, gradient:r,g,b:r,g,b or image:path (default to the one defined by the scene)")
+	fs.Float64Var(&options.EnvRotation, "env-rotation", 0, "rotation (in degrees around the vertical axis) of the environment map provided with -background image:path")
+	fs.Float64Var(&options.EnvIntensity, "env-intensity", 1.0, "intensity of the environment map provided with -background image:path")
+	options.BVH = tracer.SplitSAH
+	fs.Var(&options.BVH, "bvh", "strategy used to build the bounding volume hierarchy (midpoint, equal or sah)")
+	fs.IntVar(&options.TileSize, "tile", tracer.DefaultTileSize, "size (in pixels) of the tiles the image is split into")
+	options.TileOrder = tracer.TileSpiral
+	fs.Var(&options.TileOrder, "tile-order", "order in which the tiles are rendered (scan, spiral, hilbert or center-out)")
+	fs.Var(&options.Sampler, "sampler", "sampler used to distribute the samples (independent, stratified, halton, sobol or blue-noise)")
+}
+
+// run parses the options, builds the scene and renders it
+func run() error {
 	options := Options{}
 
-	flag.IntVar(&options.Width, "w", 800, "width in pixel")
-	flag.IntVar(&options.Height, "h", 400, "height in pixel")
-	flag.IntVar(&options.CPU, "cpu", runtime.NumCPU(), "number of CPU to use (default to number of CPU available)")
-	flag.Int64Var(&options.Seed, "seed", 2017, "seed for random number generator")
-	flag.Var(&options.RaysPerPixel, "r", "comma separated list (or multiple) rays per pixel")
-	flag.StringVar(&options.Output, "o", "", "path to file for saving (do not save if not defined)")
+	registerFlags(flag.CommandLine, &options)
 
 	flag.Parse()
 
-	if (len(options.RaysPerPixel) == 0) {
-		options.RaysPerPixel = []int{1, 99}
+	if options.ListScenes {
+		listScenes(os.Stdout)
+		return nil
 	}
 
-	// initializes the random number generator (since the scene has random spheres... to be reproducible)
-	rand.Seed(options.Seed)
-
-	// initializes SDL
-	if err := sdl.Init(sdl.INIT_VIDEO); err != nil {
-		panic(err)
+	namedScene, err := FindScene(options.SceneName)
+	if err != nil {
+		return err
 	}
-	defer sdl.Quit()
 
-	// create (and show) window
-	window, err := sdl.CreateWindow("Ray Tracing", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, int32(options.Width), int32(options.Height), sdl.WINDOW_SHOWN)
+	toneMapper, err := tracer.ParseToneMapper(options.ToneMap, options.White)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer window.Destroy()
 
-	// retrieves the screen
-	screen, err := window.GetSurface()
+	options.OutputTransform, err = tracer.ParseOutputTransform(options.Transfer)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	// clear the screen (otherwise there is garbage...)
-	err = screen.FillRect(&sdl.Rect{W: int32(options.Width), H: int32(options.Height)}, 0x00000000)
+	filter, err := tracer.ParseFilter(options.Filter, options.FilterRadius)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	//camera, world := buildWorldChapter7(options.Width, options.Height)
-	//camera, world := buildWorldMetalSpheres(options.Width, options.Height)
-	//camera, world := buildWorldDielectrics(options.Width, options.Height)
-	camera, world := buildWorldOneWeekend(options.Width, options.Height)
+	if (len(options.RaysPerPixel) == 0) {
+		options.RaysPerPixel = []int{1, 99}
+	}
 
-	scene := &Scene{width: options.Width, height: options.Height, raysPerPixel: options.RaysPerPixel, camera: camera, world: world}
-	pixels, completed := scene.Render(options.CPU)
+	// initializes the random number generator (since the scene has random spheres... to be reproducible)
+	rand.Seed(options.Seed)
 
-	// update the surface to show it
-	err = window.UpdateSurface()
-	if err != nil {
-		panic(err)
-	}
+	// render a glTF scene, a model or a scene file instead of the named scene (which is only built when used since
+	// it consumes random numbers)
+	var camera tracer.Camera
+	var world tracer.HitableList
+	var background tracer.Background
+	switch {
+	case options.GLTF != "":
+		camera, world, background, err = buildWorldGLTF(options.Width, options.Height, options.GLTF)
+		if err != nil {
+			return err
+		}
 
-	updateDisplay := true
+	case options.Model != "":
+		model, err := tracer.LoadModel(options.Model)
+		if err != nil {
+			return err
+		}
+		camera, world, background = buildWorldModel(options.Width, options.Height, model)
 
-	// poll for quit event
-	for running := true; running; {
-		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-			switch event.(type) {
-			case *sdl.QuitEvent:
-				running = false
-			}
+	case options.Scene != "":
+		camera, world, background, err = tracer.LoadSceneFile(options.Scene, options.Width, options.Height)
+		if err != nil {
+			return err
 		}
 
-		// wait a few between iterations
-		sdl.Delay(16)
-
-		if updateDisplay {
-
-			display(window, screen, scene, pixels)
-
-			// check (non blocking thanks to select) that the image is completely rendered
-			select {
-			case <-completed:
-				updateDisplay = false
-				fmt.Println("Render complete.")
-				err, saved := saveImage(pixels, options)
-				switch {
-				case err != nil:
-					fmt.Printf("Error while saving the image [%v]\n", err)
-				case saved:
-					fmt.Printf("Image saved to %v\n", options.Output)
-				}
-			default:
-				break
-			}
+	default:
+		camera, world, background = namedScene.Build(options.Width, options.Height)
+	}
 
+	// override the background defined by the scene
+	if options.Background != "" {
+		background, err = tracer.ParseBackground(options.Background)
+		if err != nil {
+			return err
+		}
+		if env, ok := background.(*tracer.EnvironmentMap); ok {
+			env.Rotation = options.EnvRotation * math.Pi / 180.0
+			env.Intensity = options.EnvIntensity
 		}
 	}
+
+	// build the bounding volume hierarchy once (before rendering)
+	bvh, stats := tracer.BuildBVH(world, options.BVH)
+	fmt.Println(stats)
+
+	scene := &tracer.Scene{Width: options.Width, Height: options.Height, RaysPerPixel: options.RaysPerPixel, Camera: camera, World: bvh, Background: background, Seed: options.Seed, ToneMapper: toneMapper, Exposure: options.Exposure, OutputTransform: options.OutputTransform, Filter: filter, TileSize: options.TileSize, TileOrder: options.TileOrder, Sampler: options.Sampler}
+
+	if options.Headless {
+		return renderHeadless(scene, options)
+	}
+
+	return renderWindow(scene, options)
+}
+
+// renderHeadless renders the scene (the progress of each pass is printed) and saves the image. Interrupting the
+// program (Ctrl-C) stops the rendering (the image is not saved)
+func renderHeadless(scene *tracer.Scene, options Options) error {
+	if options.Output == "" {
+		fmt.Println("Warning: no output file (-o), the image will not be saved")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fb, done := scene.RenderContext(ctx, options.CPU, printProgress)
+	if err := <-done; err != nil {
+		return fmt.Errorf("rendering interrupted [%v]", err)
+	}
+
+	fmt.Println("Render complete.")
+	return saveRenderedImage(fb, options)
+}
+
+// printProgress prints the progress at the end of each pass
+func printProgress(event tracer.ProgressEvent) {
+	if event.Kind == tracer.PassFinished {
+		fmt.Printf("%v (%.0f rays/s)\n", event, event.RaysPerSecond)
+	}
+}
+
+// saveRenderedImage saves the image (if requested) and reports it
+func saveRenderedImage(fb *tracer.FrameBuffer, options Options) error {
+	err, saved := saveImage(fb, options)
+	switch {
+	case err != nil:
+		return fmt.Errorf("error while saving the image [%v]", err)
+	case saved:
+		fmt.Printf("Image saved to %v\n", options.Output)
+	}
+	return nil
 }
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestFindScene(t *testing.T) {
+	names := make(map[string]bool)
+	for _, scene := range scenes {
+		if names[scene.Name] {
+			t.Errorf("duplicate scene name %v", scene.Name)
+		}
+		names[scene.Name] = true
+
+		found, err := FindScene(scene.Name)
+		if err != nil || found.Name != scene.Name {
+			t.Errorf("%v/%v [test %v]", found, err, scene.Name)
+		}
+	}
+
+	if _, err := FindScene(defaultSceneName); err != nil {
+		t.Errorf("default scene: %v", err)
+	}
+
+	_, err := FindScene("unknown")
+	if err == nil || !strings.Contains(err.Error(), "cornell-box") {
+		t.Errorf("%v should list the scenes", err)
+	}
+
+	buf := &bytes.Buffer{}
+	listScenes(buf)
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != len(scenes) {
+		t.Errorf("%v != %v lines", len(lines), len(scenes))
+	}
+}
+
+func TestRegisterFlags(t *testing.T) {
+	var tests = []struct {
+		args  []string
+		model string
+	}{
+		{[]string{}, ""},
+		{[]string{"-model", "teapot.obj"}, "teapot.obj"},
+		{[]string{"-model", "bunny.ply", "-headless", "-o", "bunny.png"}, "bunny.ply"},
+	}
+
+	for idx, test := range tests {
+		options := Options{}
+		fs := flag.NewFlagSet("ray-tracing", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		registerFlags(fs, &options)
+		if err := fs.Parse(test.args); err != nil {
+			t.Errorf("unexpected error %v [test %v]", err, idx)
+			continue
+		}
+		if options.Model != test.model {
+			t.Errorf("%v expected got %v instead [test %v]", test.model, options.Model, idx)
+		}
+	}
+}
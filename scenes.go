@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/ypujante/ray-tracing/tracer"
+)
+
+// buildWorld is the end result chapter 7
+func buildWorldChapter7(width, height int) (tracer.Camera, tracer.HitableList, tracer.Background) {
+	lookFrom := tracer.Point3{X: 0, Y: 0.0, Z: 3.0}
+	lookAt := tracer.Point3{Z: -1.0}
+	aperture := 0.0
+	distToFocus := 1.0
+	camera := tracer.NewCamera(lookFrom, lookAt, tracer.Vec3{Y: 1.0}, 20, float64(width)/float64(height), aperture, distToFocus)
+
+	world := tracer.HitableList{
+		tracer.Sphere{Center: tracer.Point3{Z: -1.0}, Radius: 0.5, Material: tracer.Lambertian{Albedo: tracer.Color{R: 1.0}}},
+		tracer.Sphere{Center: tracer.Point3{Y: -100.5, Z: -1.0}, Radius: 100, Material: tracer.Lambertian{Albedo: tracer.Color{G: 1.0}}},
+	}
+
+	return camera, world, tracer.SkyBackground
+}
+
+// buildWorldMetalSpheres is the end result chapter 8
+func buildWorldMetalSpheres(width, height int) (tracer.Camera, tracer.HitableList, tracer.Background) {
+	lookFrom := tracer.Point3{X: 0, Y: 0.0, Z: 3.0}
+	lookAt := tracer.Point3{Z: -1.0}
+	aperture := 0.0
+	distToFocus := 1.0
+	camera := tracer.NewCamera(lookFrom, lookAt, tracer.Vec3{Y: 1.0}, 20, float64(width)/float64(height), aperture, distToFocus)
+
+	world := tracer.HitableList{
+		tracer.Sphere{Center: tracer.Point3{Z: -1.0}, Radius: 0.5, Material: tracer.Lambertian{Albedo: tracer.Color{R: 0.8, G: 0.3, B: 0.3}}},
+		tracer.Sphere{Center: tracer.Point3{Y: -100.5, Z: -1.0}, Radius: 100, Material: tracer.Lambertian{Albedo: tracer.Color{R: 0.8, G: 0.8}}},
+		tracer.Sphere{Center: tracer.Point3{X: 1.0, Y: 0, Z: -1.0}, Radius: 0.5, Material: tracer.Metal{Albedo: tracer.Color{R: 0.8, G: 0.6, B: 0.2}, Fuzz: 1.0}},
+		tracer.Sphere{Center: tracer.Point3{X: -1.0, Y: 0, Z: -1.0}, Radius: 0.5, Material: tracer.Metal{Albedo: tracer.Color{R: 0.8, G: 0.8, B: 0.8}, Fuzz: 0.3}},
+	}
+
+	return camera, world, tracer.SkyBackground
+}
+
+// buildWorldDielectrics is the end result chapter 10
+func buildWorldDielectrics(width, height int) (tracer.Camera, tracer.HitableList, tracer.Background) {
+
+	lookFrom := tracer.Point3{X: -2.0, Y: 2.0, Z: 1.0}
+	lookAt := tracer.Point3{Z: -1.0}
+	aperture := 0.0
+	distToFocus := 1.0
+	camera := tracer.NewCamera(lookFrom, lookAt, tracer.Vec3{Y: 1.0}, 20, float64(width)/float64(height), aperture, distToFocus)
+
+	world := tracer.HitableList{
+		tracer.Sphere{Center: tracer.Point3{Z: -1.0}, Radius: 0.5, Material: tracer.Lambertian{Albedo: tracer.Color{R: 0.1, G: 0.2, B: 0.5}}},
+		tracer.Sphere{Center: tracer.Point3{Y: -100.5, Z: -1.0}, Radius: 100, Material: tracer.Lambertian{Albedo: tracer.Color{R: 0.8, G: 0.8}}},
+		tracer.Sphere{Center: tracer.Point3{X: 1.0, Y: 0, Z: -1.0}, Radius: 0.5, Material: tracer.Metal{Albedo: tracer.Color{R: 0.8, G: 0.6, B: 0.2}, Fuzz: 1.0}},
+		tracer.Sphere{Center: tracer.Point3{X: -1.0, Y: 0, Z: -1.0}, Radius: 0.5, Material: tracer.Dielectric{RefIdx: 1.5}},
+		tracer.Sphere{Center: tracer.Point3{X: -1.0, Y: 0, Z: -1.0}, Radius: -0.45, Material: tracer.Dielectric{RefIdx: 1.5}},
+	}
+
+	return camera, world, tracer.SkyBackground
+}
+
+// buildWorldOneWeekend is the end result book
+func buildWorldOneWeekend(width, height int) (tracer.Camera, tracer.HitableList, tracer.Background) {
+	return buildWorldRandomSpheres(width, height, tracer.Lambertian{Albedo: tracer.Color{R: 0.5, G: 0.5, B: 0.5}})
+}
+
+// buildWorldOneWeekendCheckerboard is the end result book with a checkerboard ground
+func buildWorldOneWeekendCheckerboard(width, height int) (tracer.Camera, tracer.HitableList, tracer.Background) {
+	return buildWorldRandomSpheres(width, height, tracer.Lambertian{Albedo: tracer.CheckerTexture{Odd: tracer.Color{R: 0.2, G: 0.3, B: 0.1}, Even: tracer.Color{R: 0.9, G: 0.9, B: 0.9}, Scale: 10}})
+}
+
+// buildWorldRandomSpheres generates the random spheres of the end result book on the ground material provided
+func buildWorldRandomSpheres(width, height int, ground tracer.Material) (tracer.Camera, tracer.HitableList, tracer.Background) {
+	world := []tracer.Hitable{}
+
+	maxSpheres := 500
+	world = append(world, tracer.Sphere{Center: tracer.Point3{Y: -1000.0}, Radius: 1000, Material: ground})
+
+	for a := -11; a < 11 && len(world) < maxSpheres; a++ {
+		for b := -11; b < 11 && len(world) < maxSpheres; b++ {
+			chooseMaterial := rand.Float64()
+			center := tracer.Point3{X: float64(a) + 0.9*rand.Float64(), Y: 0.2, Z: float64(b) + 0.9*rand.Float64()}
+
+			if center.Sub(tracer.Point3{X: 4.0, Y: 0.2, Z: 0}).Length() > 0.9 {
+				switch {
+				case chooseMaterial < 0.8: // diffuse
+					world = append(world,
+						tracer.Sphere{
+							Center:   center,
+							Radius:   0.2,
+							Material: tracer.Lambertian{Albedo: tracer.Color{R: rand.Float64() * rand.Float64(), G: rand.Float64() * rand.Float64(), B: rand.Float64() * rand.Float64()}}})
+				case chooseMaterial < 0.95: // metal
+					world = append(world,
+						tracer.Sphere{
+							Center:   center,
+							Radius:   0.2,
+							Material: tracer.Metal{Albedo: tracer.Color{R: 0.5 * (1 + rand.Float64()), G: 0.5 * (1 + rand.Float64()), B: 0.5 * (1 + rand.Float64())}, Fuzz: 0.5 * rand.Float64()}})
+				default:
+					world = append(world,
+						tracer.Sphere{
+							Center:   center,
+							Radius:   0.2,
+							Material: tracer.Dielectric{RefIdx: 1.5}})
+
+				}
+			}
+		}
+	}
+
+	world = append(world,
+		tracer.Sphere{
+			Center:   tracer.Point3{X: 0, Y: 1, Z: 0},
+			Radius:   1.0,
+			Material: tracer.Dielectric{RefIdx: 1.5}},
+		tracer.Sphere{
+			Center:   tracer.Point3{X: -4, Y: 1, Z: 0},
+			Radius:   1.0,
+			Material: tracer.Lambertian{Albedo: tracer.Color{R: 0.4, G: 0.2, B: 0.1}}},
+		tracer.Sphere{
+			Center:   tracer.Point3{X: 4, Y: 1, Z: 0},
+			Radius:   1.0,
+			Material: tracer.Metal{Albedo: tracer.Color{R: 0.7, G: 0.6, B: 0.5}, Fuzz: 0}})
+
+	lookFrom := tracer.Point3{X: 13, Y: 2, Z: 3}
+	lookAt := tracer.Point3{}
+	aperture := 0.1
+	distToFocus := 10.0
+	camera := tracer.NewCamera(lookFrom, lookAt, tracer.Vec3{Y: 1.0}, 20, float64(width)/float64(height), aperture, distToFocus)
+
+	return camera, world, tracer.SkyBackground
+}
+
+// buildWorldPerlin shows the procedural (Perlin noise) textures: turbulence, marble and wood
+func buildWorldPerlin(width, height int) (tracer.Camera, tracer.HitableList, tracer.Background) {
+	// the noise is generated from the seed so that the result is reproducible
+	perlin := tracer.NewPerlin(rand.New(rand.NewSource(rand.Int63())))
+
+	world := tracer.HitableList{
+		tracer.Sphere{Center: tracer.Point3{Y: -1000.0}, Radius: 1000, Material: tracer.Lambertian{Albedo: tracer.NewNoiseTexture(perlin, tracer.NoiseTurbulence, 2, 7, tracer.Color{R: 0.1, G: 0.2, B: 0.1}, tracer.Color{R: 0.8, G: 0.8, B: 0.7})}},
+		tracer.Sphere{Center: tracer.Point3{X: -2.2, Y: 1}, Radius: 1, Material: tracer.Lambertian{Albedo: tracer.NewNoiseTexture(perlin, tracer.NoiseMarble, 4, 7, tracer.Color{R: 0.1, G: 0.1, B: 0.15}, tracer.Color{R: 0.95, G: 0.95, B: 0.9})}},
+		tracer.Sphere{Center: tracer.Point3{Y: 1}, Radius: 1, Material: tracer.Lambertian{Albedo: tracer.NewNoiseTexture(perlin, tracer.NoiseWood, 6, 3, tracer.Color{R: 0.4, G: 0.2, B: 0.07}, tracer.Color{R: 0.75, G: 0.5, B: 0.25})}},
+		tracer.Sphere{Center: tracer.Point3{X: 2.2, Y: 1}, Radius: 1, Material: tracer.Metal{Albedo: tracer.NewNoiseTexture(perlin, tracer.NoiseSmooth, 3, 1, tracer.Color{R: 0.7, G: 0.6, B: 0.5}, tracer.Color{R: 0.9, G: 0.9, B: 0.9}), Fuzz: 0.1}},
+	}
+
+	lookFrom := tracer.Point3{X: 0, Y: 3, Z: 12}
+	lookAt := tracer.Point3{Y: 1}
+	aperture := 0.0
+	distToFocus := 10.0
+	camera := tracer.NewCamera(lookFrom, lookAt, tracer.Vec3{Y: 1.0}, 30, float64(width)/float64(height), aperture, distToFocus)
+
+	return camera, world, tracer.SkyBackground
+}
+
+// buildWorldCornellBox is a Cornell box lit only by an area light in the ceiling (black background)
+func buildWorldCornellBox(width, height int) (tracer.Camera, tracer.HitableList, tracer.Background) {
+	red := tracer.Lambertian{Albedo: tracer.Color{R: 0.65, G: 0.05, B: 0.05}}
+	white := tracer.Lambertian{Albedo: tracer.Color{R: 0.73, G: 0.73, B: 0.73}}
+	green := tracer.Lambertian{Albedo: tracer.Color{R: 0.12, G: 0.45, B: 0.15}}
+	light := tracer.DiffuseLight{Emit: tracer.Color{R: 15, G: 15, B: 15}}
+
+	world := tracer.HitableList{
+		tracer.FlipNormals{Hitable: tracer.YZRect{Y0: 0, Y1: 555, Z0: 0, Z1: 555, K: 555, Material: green}},
+		tracer.YZRect{Y0: 0, Y1: 555, Z0: 0, Z1: 555, K: 0, Material: red},
+		tracer.FlipNormals{Hitable: tracer.XZRect{X0: 213, X1: 343, Z0: 227, Z1: 332, K: 554, Material: light}},
+		tracer.FlipNormals{Hitable: tracer.XZRect{X0: 0, X1: 555, Z0: 0, Z1: 555, K: 555, Material: white}},
+		tracer.XZRect{X0: 0, X1: 555, Z0: 0, Z1: 555, K: 0, Material: white},
+		tracer.FlipNormals{Hitable: tracer.XYRect{X0: 0, X1: 555, Y0: 0, Y1: 555, K: 555, Material: white}},
+		tracer.Sphere{Center: tracer.Point3{X: 190, Y: 90, Z: 190}, Radius: 90, Material: tracer.Dielectric{RefIdx: 1.5}},
+	}
+	world = append(world, tracer.NewBox(tracer.Point3{X: 265, Y: 0, Z: 295}, tracer.Point3{X: 430, Y: 330, Z: 460}, white)...)
+
+	lookFrom := tracer.Point3{X: 278, Y: 278, Z: -800}
+	lookAt := tracer.Point3{X: 278, Y: 278, Z: 0}
+	aperture := 0.0
+	distToFocus := 10.0
+	camera := tracer.NewCamera(lookFrom, lookAt, tracer.Vec3{Y: 1.0}, 40, float64(width)/float64(height), aperture, distToFocus)
+
+	return camera, world, tracer.BlackBackground
+}
+
+// buildWorldModel frames the model (ex: loaded from an OBJ file) with the camera and puts it on a ground plane
+func buildWorldModel(width, height int, model tracer.HitableList) (tracer.Camera, tracer.HitableList, tracer.Background) {
+	ok, box := model.BoundingBox()
+	if !ok {
+		box = &tracer.AABB{Min: tracer.Point3{X: -1, Y: -1, Z: -1}, Max: tracer.Point3{X: 1, Y: 1, Z: 1}}
+	}
+
+	center := box.Centroid()
+	radius := box.Max.Sub(box.Min).Length() / 2.0
+
+	ground := 100.0 * radius
+	world := append(tracer.HitableList{
+		tracer.XZRect{X0: center.X - ground, X1: center.X + ground, Z0: center.Z - ground, Z1: center.Z + ground, K: box.Min.Y, Material: tracer.Lambertian{Albedo: tracer.Color{R: 0.5, G: 0.5, B: 0.5}}},
+	}, model...)
+
+	vfov := 30.0
+	distance := 1.1 * radius / math.Sin(vfov*math.Pi/360.0)
+	lookFrom := center.Translate(tracer.Vec3{X: 0.5, Y: 0.35, Z: 1.0}.Unit().Scale(distance))
+	aperture := 0.0
+	camera := tracer.NewCamera(lookFrom, center, tracer.Vec3{Y: 1.0}, vfov, float64(width)/float64(height), aperture, distance)
+
+	return camera, world, tracer.SkyBackground
+}
+
+// buildWorldGLTF loads a glTF scene using its camera (the scene is framed like a model when there is no camera)
+func buildWorldGLTF(width, height int, path string) (tracer.Camera, tracer.HitableList, tracer.Background, error) {
+	camera, world, err := tracer.LoadGLTF(path, float64(width)/float64(height))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if camera == nil {
+		camera, world, background := buildWorldModel(width, height, world)
+		return camera, world, background, nil
+	}
+
+	return camera, world, tracer.SkyBackground, nil
+}
+
+// SceneBuilder builds the camera, world and background of a scene for the given image size
+type SceneBuilder func(width, height int) (tracer.Camera, tracer.HitableList, tracer.Background)
+
+// NamedScene is a built-in scene which can be selected from the command line (-scene-name)
+type NamedScene struct {
+	Name        string
+	Description string
+	Build       SceneBuilder
+}
+
+// defaultSceneName is the scene rendered when none is selected
+const defaultSceneName = "one-weekend"
+
+// scenes is the registry of built-in scenes (in the order they are listed by -list-scenes)
+var scenes = []NamedScene{
+	{"chapter7", "diffuse sphere on a green ground (end result chapter 7)", buildWorldChapter7},
+	{"metal-spheres", "diffuse sphere between 2 metal spheres (end result chapter 8)", buildWorldMetalSpheres},
+	{"dielectrics", "diffuse, metal and hollow glass spheres (end result chapter 10)", buildWorldDielectrics},
+	{"one-weekend", "random spheres (end result book)", buildWorldOneWeekend},
+	{"one-weekend-checkerboard", "random spheres on a checkerboard ground", buildWorldOneWeekendCheckerboard},
+	{"perlin", "procedural textures: turbulence, marble and wood", buildWorldPerlin},
+	{"cornell-box", "Cornell box lit only by an area light", buildWorldCornellBox},
+}
+
+// FindScene returns the built-in scene with the given name
+func FindScene(name string) (*NamedScene, error) {
+	names := make([]string, len(scenes))
+	for i := range scenes {
+		if scenes[i].Name == name {
+			return &scenes[i], nil
+		}
+		names[i] = scenes[i].Name
+	}
+	return nil, fmt.Errorf("unknown scene [%v] (must be one of %v)", name, strings.Join(names, ", "))
+}
+
+// listScenes prints the built-in scenes with their description
+func listScenes(w io.Writer) {
+	for _, scene := range scenes {
+		fmt.Fprintf(w, "%-26v %v\n", scene.Name, scene.Description)
+	}
+}
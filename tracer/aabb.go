@@ -0,0 +1,85 @@
+package tracer
+
+import (
+	"math"
+)
+
+/***********************
+ * AABB
+ ************************/
+// AABB defines an axis aligned bounding box (defined by its 2 opposite corners)
+type AABB struct {
+	Min, Max Point3
+}
+
+// Hit returns true if the ray intersects the box between tMin and tMax (slab method)
+func (box *AABB) Hit(r *Ray, tMin float64, tMax float64) bool {
+	origin := [3]float64{r.Origin.X, r.Origin.Y, r.Origin.Z}
+	direction := [3]float64{r.Direction.X, r.Direction.Y, r.Direction.Z}
+	min := [3]float64{box.Min.X, box.Min.Y, box.Min.Z}
+	max := [3]float64{box.Max.X, box.Max.Y, box.Max.Z}
+
+	for a := 0; a < 3; a++ {
+		invD := 1.0 / direction[a]
+		t0 := (min[a] - origin[a]) * invD
+		t1 := (max[a] - origin[a]) * invD
+		if invD < 0.0 {
+			t0, t1 = t1, t0
+		}
+		if t0 > tMin {
+			tMin = t0
+		}
+		if t1 < tMax {
+			tMax = t1
+		}
+		if tMax <= tMin {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Centroid returns the center of the box
+func (box *AABB) Centroid() Point3 {
+	return Point3{(box.Min.X + box.Max.X) / 2.0, (box.Min.Y + box.Max.Y) / 2.0, (box.Min.Z + box.Max.Z) / 2.0}
+}
+
+// SurfaceArea returns the area of the 6 faces of the box
+func (box *AABB) SurfaceArea() float64 {
+	d := box.Max.Sub(box.Min)
+	return 2.0 * (d.X*d.Y + d.Y*d.Z + d.Z*d.X)
+}
+
+// LongestAxis returns the axis (0 for X, 1 for Y, 2 for Z) along which the box is the biggest
+func (box *AABB) LongestAxis() int {
+	d := box.Max.Sub(box.Min)
+	switch {
+	case d.X >= d.Y && d.X >= d.Z:
+		return 0
+	case d.Y >= d.Z:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// SurroundingBox returns the smallest box which contains both boxes
+func SurroundingBox(box0 *AABB, box1 *AABB) *AABB {
+	return &AABB{
+		Min: Point3{math.Min(box0.Min.X, box1.Min.X), math.Min(box0.Min.Y, box1.Min.Y), math.Min(box0.Min.Z, box1.Min.Z)},
+		Max: Point3{math.Max(box0.Max.X, box1.Max.X), math.Max(box0.Max.Y, box1.Max.Y), math.Max(box0.Max.Z, box1.Max.Z)},
+	}
+}
+
+// axis returns the coordinate of the point along the axis (0 for X, 1 for Y, 2 for Z)
+func (p Point3) axis(a int) float64 {
+	switch a {
+	case 0:
+		return p.X
+	case 1:
+		return p.Y
+	default:
+		return p.Z
+	}
+}
@@ -0,0 +1,156 @@
+package tracer
+
+import (
+	"math"
+	"sync"
+)
+
+// AccumulationBuffer holds, for every pixel, the sum of the (linear, unclamped) colors of all the rays cast through
+// it weighted by the reconstruction filter (see Filter) and the sum of the weights (which is the number of rays
+// for the default box filter). It is the full precision result of the rendering: the pixels of the FrameBuffer
+// (8 bits per channel) are derived from it for display only. The samples of a pass are added at the end of the
+// pass while it can be read at any time (every access is synchronized).
+type AccumulationBuffer struct {
+	width, height int
+	mutex         sync.RWMutex
+	sum           []Color
+	weight        []float64
+}
+
+// NewAccumulationBuffer creates an (empty) accumulation buffer of width x height pixels
+func NewAccumulationBuffer(width, height int) *AccumulationBuffer {
+	return &AccumulationBuffer{width: width, height: height, sum: make([]Color, width*height), weight: make([]float64, width*height)}
+}
+
+// Width returns the width (in pixels) of the buffer
+func (ab *AccumulationBuffer) Width() int {
+	return ab.width
+}
+
+// Height returns the height (in pixels) of the buffer
+func (ab *AccumulationBuffer) Height() int {
+	return ab.height
+}
+
+// Add accumulates samples whose weighted colors add up to sum and whose weights add up to weight in the pixel x/y
+// (in image coordinates: y = 0 is the top row)
+func (ab *AccumulationBuffer) Add(x, y int, sum Color, weight float64) {
+	ab.mutex.Lock()
+	defer ab.mutex.Unlock()
+	k := y*ab.width + x
+	ab.sum[k] = ab.sum[k].Add(sum)
+	ab.weight[k] += weight
+}
+
+// At returns the weighted sum of the colors and the sum of the weights of the pixel x/y (in image coordinates)
+func (ab *AccumulationBuffer) At(x, y int) (Color, float64) {
+	ab.mutex.RLock()
+	defer ab.mutex.RUnlock()
+	k := y*ab.width + x
+	return ab.sum[k], ab.weight[k]
+}
+
+// Mean returns the average color of the pixel x/y (in image coordinates) or Black if it has no sample yet
+func (ab *AccumulationBuffer) Mean(x, y int) Color {
+	sum, weight := ab.At(x, y)
+	return mean(sum, weight)
+}
+
+// Image returns the average color of every pixel (pixels without sample are Black)
+func (ab *AccumulationBuffer) Image() *HDRImage {
+	ab.mutex.RLock()
+	defer ab.mutex.RUnlock()
+	pixels := make([]Color, len(ab.sum))
+	for k := range pixels {
+		pixels[k] = mean(ab.sum[k], ab.weight[k])
+	}
+	return &HDRImage{Width: ab.width, Height: ab.height, Pixels: pixels}
+}
+
+// addTile accumulates the samples of a tile (see tileBuffer)
+func (ab *AccumulationBuffer) addTile(tb *tileBuffer) {
+	ab.mutex.Lock()
+	defer ab.mutex.Unlock()
+	for j := 0; j < tb.height; j++ {
+		for i := 0; i < tb.width; i++ {
+			l := j*tb.width + i
+			if tb.weight[l] == 0 {
+				continue
+			}
+			k := (tb.y0+j)*ab.width + tb.x0 + i
+			ab.sum[k] = ab.sum[k].Add(tb.sum[l])
+			ab.weight[k] += tb.weight[l]
+		}
+	}
+}
+
+// means returns the average colors of several pixels (identified by their index k) in means. When tb is not nil, its
+// samples are included (the pixels must belong to it) without being accumulated
+func (ab *AccumulationBuffer) means(ks []int, tb *tileBuffer, means []Color) {
+	ab.mutex.RLock()
+	defer ab.mutex.RUnlock()
+	for i, k := range ks {
+		sum, weight := ab.sum[k], ab.weight[k]
+		if tb != nil {
+			l := tb.index(k%ab.width, k/ab.width)
+			sum, weight = sum.Add(tb.sum[l]), weight+tb.weight[l]
+		}
+		means[i] = mean(sum, weight)
+	}
+}
+
+func mean(sum Color, weight float64) Color {
+	if weight <= 0 {
+		return Black
+	}
+	return sum.Scale(1.0 / weight)
+}
+
+// tileBuffer holds the samples of a tile during a pass: since a sample contributes to the neighboring pixels (see
+// Filter), the buffer covers the tile plus a margin (the radius of the filter) clipped to the image. The buffers are
+// only accumulated (see AccumulationBuffer.addTile) at the end of the pass in a fixed order so that the result does
+// not depend on the order in which the tiles have been rendered.
+type tileBuffer struct {
+	x0, y0, width, height int
+	sum                   []Color
+	weight                []float64
+}
+
+// newTileBuffer creates the buffer for the tile (with margin pixels around it) of an image width x height
+func newTileBuffer(tile Tile, margin, width, height int) *tileBuffer {
+	x0, y0 := maxInt(tile.X0-margin, 0), maxInt(tile.Y0-margin, 0)
+	x1, y1 := minInt(tile.X1+margin, width), minInt(tile.Y1+margin, height)
+	w, h := x1-x0, y1-y0
+	return &tileBuffer{x0: x0, y0: y0, width: w, height: h, sum: make([]Color, w*h), weight: make([]float64, w*h)}
+}
+
+// index returns the index in the buffer of the pixel x/y (in image coordinates)
+func (tb *tileBuffer) index(x, y int) int {
+	return (y-tb.y0)*tb.width + x - tb.x0
+}
+
+// splat adds the sample whose position is sx/sy (in image coordinates, pixel x/y covers [x,x+1) x [y,y+1)) to every
+// pixel within the radius of the filter
+func (tb *tileBuffer) splat(filter Filter, sx, sy float64, c Color) {
+	r := filter.Radius()
+	x0, x1 := maxInt(int(math.Floor(sx-r)), tb.x0), minInt(int(math.Floor(sx+r)), tb.x0+tb.width-1)
+	y0, y1 := maxInt(int(math.Floor(sy-r)), tb.y0), minInt(int(math.Floor(sy+r)), tb.y0+tb.height-1)
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			w := filter.Evaluate(sx-(float64(x)+0.5), sy-(float64(y)+0.5))
+			if w == 0 {
+				continue
+			}
+			l := tb.index(x, y)
+			tb.sum[l] = tb.sum[l].Add(c.Scale(w))
+			tb.weight[l] += w
+		}
+	}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
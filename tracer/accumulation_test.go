@@ -0,0 +1,65 @@
+package tracer
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAccumulationBuffer(t *testing.T) {
+	ab := NewAccumulationBuffer(3, 2)
+
+	ab.Add(1, 0, Color{1, 2, 3}, 2)
+	ab.Add(1, 0, Color{3, 2, 1}, 2)
+	ab.Add(2, 1, Color{10, 20, 30}, 10)
+
+	if sum, count := ab.At(1, 0); sum != (Color{4, 4, 4}) || count != 4 {
+		t.Errorf("%v/%v expected got %v/%v instead", Color{4, 4, 4}, 4, sum, count)
+	}
+
+	var tests = []struct {
+		x, y     int
+		expected Color
+	}{
+		{1, 0, Color{1, 1, 1}},
+		{2, 1, Color{1, 2, 3}},
+		{0, 0, Black},
+	}
+
+	img := ab.Image()
+	for idx, test := range tests {
+		if c := ab.Mean(test.x, test.y); c != test.expected {
+			t.Errorf("%v expected got %v instead [test %v]", test.expected, c, idx)
+		}
+		if c := img.At(test.x, test.y); c != test.expected {
+			t.Errorf("%v expected got %v instead (image) [test %v]", test.expected, c, idx)
+		}
+	}
+}
+
+func TestRenderContext_Accumulation(t *testing.T) {
+	scene := testScene(16, 8, 1, 3)
+	scene.Background = ConstantBackground{Color{2, 4, 0.5}}
+
+	fb, done := scene.RenderContext(context.Background(), 3, nil)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	ab := fb.Accumulation()
+	for y := 0; y < scene.Height; y++ {
+		for x := 0; x < scene.Width; x++ {
+			sum, count := ab.At(x, y)
+			if count != 4 {
+				t.Fatalf("4 samples expected got %v instead (%v/%v)", count, x, y)
+			}
+			if fb.At(x, y) != scene.displayValue(sum.Scale(0.25)) {
+				t.Errorf("pixel %v/%v not derived from the accumulation buffer", x, y)
+			}
+		}
+	}
+
+	// the corners only see the (unclamped) background
+	if c := ab.Mean(0, 0); c != (Color{2, 4, 0.5}) {
+		t.Errorf("%v expected got %v instead", Color{2, 4, 0.5}, c)
+	}
+}
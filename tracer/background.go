@@ -0,0 +1,142 @@
+package tracer
+
+import (
+	"fmt"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/***********************
+ * Background
+ ************************/
+// Background defines the color of a ray which does not hit anything in the world
+type Background interface {
+	Color(r *Ray) Color
+}
+
+// SkyBackground is the white to blue gradient used in the book
+var SkyBackground = GradientBackground{Bottom: White, Top: Color{0.5, 0.7, 1.0}}
+
+// BlackBackground is used for scenes lit only by emissive materials
+var BlackBackground = ConstantBackground{Black}
+
+/***********************
+ * ConstantBackground
+ ************************/
+// ConstantBackground is a background of a single color (ex: neutral backdrop)
+type ConstantBackground struct {
+	Value Color
+}
+
+func (cb ConstantBackground) Color(r *Ray) Color {
+	return cb.Value
+}
+
+/***********************
+ * GradientBackground
+ ************************/
+// GradientBackground is a vertical gradient based on the direction of the ray (from bottom when pointing down
+// to top when pointing up)
+type GradientBackground struct {
+	Bottom, Top Color
+}
+
+func (gb GradientBackground) Color(r *Ray) Color {
+	unitDirection := r.Direction.Unit()
+	t := 0.5 * (unitDirection.Y + 1.0)
+
+	return gb.Bottom.Scale(1.0 - t).Add(gb.Top.Scale(t))
+}
+
+// sampledBackground is implemented by backgrounds which can be sampled explicitly (see EnvironmentMap.sample)
+type sampledBackground interface {
+	Background
+	sample(rnd Rnd) (direction Vec3, radiance Color, pdf float64)
+}
+
+// directionToEquirectangular converts a unit direction into (u,v) coordinates in [0,1] of an equirectangular
+// projection: u is the longitude (0.5 is -Z) and v the latitude (0 is +Y)
+func directionToEquirectangular(d Vec3) (u, v float64) {
+	u = 0.5 + math.Atan2(d.X, -d.Z)/(2*math.Pi)
+	v = math.Acos(math.Max(-1.0, math.Min(1.0, d.Y))) / math.Pi
+	return
+}
+
+// equirectangularToDirection is the inverse of directionToEquirectangular
+func equirectangularToDirection(u, v float64) Vec3 {
+	phi := (u - 0.5) * 2 * math.Pi
+	theta := v * math.Pi
+	sinTheta := math.Sin(theta)
+	return Vec3{sinTheta * math.Sin(phi), math.Cos(theta), -sinTheta * math.Cos(phi)}
+}
+
+/***********************
+ * Parsing
+ ************************/
+// ParseBackground creates a background from its textual description (used on the command line)
+//	sky                  => the white to blue gradient of the book
+//	black / white        => constant color
+//	color:r,g,b          => constant color (ex: color:0.5,0.5,0.5)
+//	gradient:r,g,b:r,g,b => gradient from bottom to top color
+//	image:path           => equirectangular environment map (Radiance .hdr, .pfm, png or jpeg image)
+func ParseBackground(spec string) (Background, error) {
+	kind, args := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		kind, args = spec[:i], spec[i+1:]
+	}
+
+	switch kind {
+	case "sky":
+		return SkyBackground, nil
+	case "black":
+		return BlackBackground, nil
+	case "white":
+		return ConstantBackground{White}, nil
+	case "color":
+		c, err := parseColor(args)
+		if err != nil {
+			return nil, fmt.Errorf("invalid background [%v]: %v", spec, err)
+		}
+		return ConstantBackground{c}, nil
+	case "gradient":
+		colors := strings.Split(args, ":")
+		if len(colors) != 2 {
+			return nil, fmt.Errorf("invalid background [%v]: expected gradient:r,g,b:r,g,b", spec)
+		}
+		bottom, err := parseColor(colors[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid background [%v]: %v", spec, err)
+		}
+		top, err := parseColor(colors[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid background [%v]: %v", spec, err)
+		}
+		return GradientBackground{bottom, top}, nil
+	case "image":
+		return LoadEnvironmentMap(args, 0, 1.0)
+	}
+
+	return nil, fmt.Errorf("unknown background [%v] (must be one of sky, black, white, color:r,g,b, gradient:r,g,b:r,g,b or image:path)", spec)
+}
+
+// parseColor parses a color of the form r,g,b
+func parseColor(s string) (Color, error) {
+	components := strings.Split(s, ",")
+	if len(components) != 3 {
+		return Black, fmt.Errorf("expected r,g,b got [%v]", s)
+	}
+
+	var rgb [3]float64
+	for i, c := range components {
+		f, err := strconv.ParseFloat(strings.TrimSpace(c), 64)
+		if err != nil {
+			return Black, err
+		}
+		rgb[i] = f
+	}
+
+	return Color{rgb[0], rgb[1], rgb[2]}, nil
+}
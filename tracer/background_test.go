@@ -0,0 +1,53 @@
+package tracer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEquirectangular_RoundTrip(t *testing.T) {
+	var tests = []Vec3{
+		{0, 0, -1},
+		{1, 0, 0},
+		{0, 1, 0},
+		{0, -1, 0},
+		Vec3{1, 2, 3}.Unit(),
+		Vec3{-0.3, -0.5, 0.8}.Unit(),
+	}
+
+	for idx, d := range tests {
+		u, v := directionToEquirectangular(d)
+		if u < 0 || u > 1 || v < 0 || v > 1 {
+			t.Errorf("%v/%v out of range [test %v]", u, v, idx)
+		}
+		r := equirectangularToDirection(u, v)
+		if math.Abs(r.X-d.X) > 1e-9 || math.Abs(r.Y-d.Y) > 1e-9 || math.Abs(r.Z-d.Z) > 1e-9 {
+			t.Errorf("%v expected got %v instead [test %v]", d, r, idx)
+		}
+	}
+}
+
+func TestParseBackground(t *testing.T) {
+	var tests = []struct {
+		spec     string
+		expected Background
+	}{
+		{"sky", SkyBackground},
+		{"black", BlackBackground},
+		{"color:0.5,0.25,1", ConstantBackground{Color{0.5, 0.25, 1}}},
+		{"gradient:0,0,0:1,1,1", GradientBackground{Black, White}},
+	}
+
+	for idx, test := range tests {
+		b, err := ParseBackground(test.spec)
+		if err != nil || b != test.expected {
+			t.Errorf("%v expected got %v (%v) instead [test %v]", test.expected, b, err, idx)
+		}
+	}
+
+	for _, spec := range []string{"", "foo", "color:1,2", "gradient:1,1,1"} {
+		if _, err := ParseBackground(spec); err == nil {
+			t.Errorf("expected error for [%v]", spec)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package tracer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/***********************
+ * BVH
+ ************************/
+// BVHNode is a node in a bounding volume hierarchy: a ray which does not hit the box of the node cannot hit
+// any of its children, thus avoiding to check every object for every ray
+type BVHNode struct {
+	left, right Hitable
+	box         AABB
+}
+
+// SplitStrategy defines how the primitives of a node are split in 2 children when building the hierarchy
+type SplitStrategy int
+
+const (
+	// SplitMidpoint splits at the middle of the longest axis of the centroids
+	SplitMidpoint SplitStrategy = iota
+	// SplitEqualCounts splits in 2 halves containing the same number of primitives (median)
+	SplitEqualCounts
+	// SplitSAH splits where the surface area heuristic estimates that traversal cost is minimal (binned)
+	SplitSAH
+)
+
+var splitStrategyNames = []string{"midpoint", "equal", "sah"}
+
+func (s SplitStrategy) String() string {
+	if s < 0 || int(s) >= len(splitStrategyNames) {
+		return fmt.Sprintf("SplitStrategy(%d)", int(s))
+	}
+	return splitStrategyNames[s]
+}
+
+// Set allows SplitStrategy to be used on the command line (flag)
+// Example: ray-tracing -bvh sah
+func (s *SplitStrategy) Set(value string) error {
+	for i, name := range splitStrategyNames {
+		if value == name {
+			*s = SplitStrategy(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown split strategy [%v] (must be one of %v)", value, strings.Join(splitStrategyNames, ", "))
+}
+
+const (
+	// cost of traversing a node relative to the cost of intersecting a primitive (used by SAH)
+	bvhTraversalCost    = 0.125
+	bvhIntersectionCost = 1.0
+	// number of bins used by the binned SAH builder
+	bvhSAHBins = 16
+	// maximum number of primitives that the SAH builder puts in a leaf
+	bvhMaxLeafSize = 4
+)
+
+// BVHStats reports statistics about a built hierarchy
+//	LeafSizes is an histogram: LeafSizes[n] is the number of leaves containing n primitives
+//	EstimatedCost is the surface area heuristic cost of the whole tree (relative to intersecting 1 primitive)
+type BVHStats struct {
+	Strategy      SplitStrategy
+	Primitives    int
+	Unbounded     int
+	Nodes         int
+	InteriorNodes int
+	Leaves        int
+	MaxDepth      int
+	LeafSizes     map[int]int
+	EstimatedCost float64
+}
+
+func (s *BVHStats) String() string {
+	sizes := make([]int, 0, len(s.LeafSizes))
+	for size := range s.LeafSizes {
+		sizes = append(sizes, size)
+	}
+	sort.Ints(sizes)
+
+	histogram := make([]string, len(sizes))
+	for i, size := range sizes {
+		histogram[i] = fmt.Sprintf("%v:%v", size, s.LeafSizes[size])
+	}
+
+	return fmt.Sprintf("BVH [%v] %v primitives (%v unbounded), %v nodes (%v interior, %v leaves), depth %v, leaf sizes {%v}, estimated cost %.2f",
+		s.Strategy, s.Primitives, s.Unbounded, s.Nodes, s.InteriorNodes, s.Leaves, s.MaxDepth, strings.Join(histogram, " "), s.EstimatedCost)
+}
+
+// NewBVH builds a bounding volume hierarchy from the list of hitables using the equal counts strategy
+// (see BuildBVH)
+func NewBVH(hl HitableList) Hitable {
+	bvh, _ := BuildBVH(hl, SplitEqualCounts)
+	return bvh
+}
+
+// BuildBVH builds a bounding volume hierarchy from the list of hitables using the provided strategy. The hierarchy
+// is a drop-in replacement for the list (the same ray hits the same object). Objects that cannot be bounded
+// (boundingBox returns false) are kept in a list alongside the hierarchy.
+// Implementation note: the split axis is chosen deterministically (longest axis of the centroids) instead of
+// randomly (as in the book) so that building the hierarchy does not consume the global random number generator
+// and the rendered image remains the same for a given seed
+func BuildBVH(hl HitableList, strategy SplitStrategy) (Hitable, *BVHStats) {
+	var bounded []bvhPrimitive
+	var unbounded HitableList
+
+	for _, h := range hl {
+		if ok, box := h.BoundingBox(); ok {
+			bounded = append(bounded, bvhPrimitive{h, *box, box.Centroid()})
+		} else {
+			unbounded = append(unbounded, h)
+		}
+	}
+
+	stats := &BVHStats{Strategy: strategy, Primitives: len(bounded), Unbounded: len(unbounded), LeafSizes: make(map[int]int)}
+
+	if len(bounded) == 0 {
+		return hl, stats
+	}
+
+	builder := bvhBuilder{strategy: strategy, stats: stats}
+	root := builder.build(bounded, 1)
+
+	// the cost is relative to the root surface area
+	if rootArea := builder.rootArea; rootArea > 0 {
+		stats.EstimatedCost /= rootArea
+	}
+
+	if len(unbounded) == 0 {
+		return root, stats
+	}
+
+	return append(HitableList{root}, unbounded...), stats
+}
+
+// bvhPrimitive caches the bounding box (and its centroid) of a hitable while building the hierarchy
+type bvhPrimitive struct {
+	hitable  Hitable
+	box      AABB
+	centroid Point3
+}
+
+// bvhBuilder holds the state while building the hierarchy
+type bvhBuilder struct {
+	strategy SplitStrategy
+	stats    *BVHStats
+	rootArea float64
+}
+
+// build recursively splits the primitives in 2 children according to the strategy
+func (b *bvhBuilder) build(primitives []bvhPrimitive, depth int) Hitable {
+	box := primitives[0].box
+	centroids := AABB{primitives[0].centroid, primitives[0].centroid}
+	for _, p := range primitives[1:] {
+		box = *SurroundingBox(&box, &p.box)
+		centroids = *SurroundingBox(&centroids, &AABB{p.centroid, p.centroid})
+	}
+
+	area := box.SurfaceArea()
+	if depth == 1 {
+		b.rootArea = area
+	}
+
+	if depth > b.stats.MaxDepth {
+		b.stats.MaxDepth = depth
+	}
+
+	if len(primitives) == 1 {
+		return b.leaf(primitives, area)
+	}
+
+	axis := centroids.LongestAxis()
+
+	// stable sort so that the hierarchy does not depend on the sort implementation
+	sort.SliceStable(primitives, func(i, j int) bool {
+		return primitives[i].centroid.axis(axis) < primitives[j].centroid.axis(axis)
+	})
+
+	var mid int
+
+	switch b.strategy {
+	case SplitMidpoint:
+		mid = splitMidpoint(primitives, &centroids, axis)
+	case SplitSAH:
+		var makeLeaf bool
+		mid, makeLeaf = splitSAH(primitives, &centroids, axis, area)
+		if makeLeaf {
+			return b.leaf(primitives, area)
+		}
+	default:
+		mid = len(primitives) / 2
+	}
+
+	b.stats.Nodes++
+	b.stats.InteriorNodes++
+	b.stats.EstimatedCost += bvhTraversalCost * area
+
+	return &BVHNode{
+		left:  b.build(primitives[:mid], depth+1),
+		right: b.build(primitives[mid:], depth+1),
+		box:   box,
+	}
+}
+
+// leaf creates a leaf (the hitable itself when only one primitive, otherwise a list)
+func (b *bvhBuilder) leaf(primitives []bvhPrimitive, area float64) Hitable {
+	b.stats.Nodes++
+	b.stats.Leaves++
+	b.stats.LeafSizes[len(primitives)]++
+	b.stats.EstimatedCost += bvhIntersectionCost * float64(len(primitives)) * area
+
+	if len(primitives) == 1 {
+		return primitives[0].hitable
+	}
+
+	hl := make(HitableList, len(primitives))
+	for i, p := range primitives {
+		hl[i] = p.hitable
+	}
+	return hl
+}
+
+// splitMidpoint returns the index of the first primitive (sorted along axis) whose centroid is past the middle
+// of the centroids box. Falls back to equal counts when all centroids end up on one side.
+func splitMidpoint(primitives []bvhPrimitive, centroids *AABB, axis int) int {
+	midpoint := (centroids.Min.axis(axis) + centroids.Max.axis(axis)) / 2.0
+	mid := sort.Search(len(primitives), func(i int) bool {
+		return primitives[i].centroid.axis(axis) >= midpoint
+	})
+	if mid == 0 || mid == len(primitives) {
+		mid = len(primitives) / 2
+	}
+	return mid
+}
+
+// splitSAH buckets the primitives (sorted along axis) in bins and returns the index of the split which has the
+// minimum estimated cost. Returns true when creating a leaf is cheaper than splitting.
+func splitSAH(primitives []bvhPrimitive, centroids *AABB, axis int, area float64) (int, bool) {
+	n := len(primitives)
+	min, max := centroids.Min.axis(axis), centroids.Max.axis(axis)
+
+	// all centroids are at the same location => no way to split spatially
+	if max <= min {
+		if n <= bvhMaxLeafSize {
+			return 0, true
+		}
+		return n / 2, false
+	}
+
+	type bin struct {
+		count int
+		box   AABB
+	}
+	var bins [bvhSAHBins]bin
+
+	binIndex := func(p *bvhPrimitive) int {
+		b := int(bvhSAHBins * (p.centroid.axis(axis) - min) / (max - min))
+		if b >= bvhSAHBins {
+			b = bvhSAHBins - 1
+		}
+		return b
+	}
+
+	for i := range primitives {
+		b := &bins[binIndex(&primitives[i])]
+		if b.count == 0 {
+			b.box = primitives[i].box
+		} else {
+			b.box = *SurroundingBox(&b.box, &primitives[i].box)
+		}
+		b.count++
+	}
+
+	// sweep from the right to compute the area/count of every right side
+	var rightArea [bvhSAHBins]float64
+	var rightCount [bvhSAHBins]int
+	var box *AABB
+	count := 0
+	for i := bvhSAHBins - 1; i > 0; i-- {
+		if bins[i].count > 0 {
+			if box == nil {
+				box = &bins[i].box
+			} else {
+				box = SurroundingBox(box, &bins[i].box)
+			}
+			count += bins[i].count
+		}
+		rightCount[i] = count
+		if box != nil {
+			rightArea[i] = box.SurfaceArea()
+		}
+	}
+
+	// sweep from the left to find the best split (split i puts bins [0,i) on the left)
+	bestCost, bestSplit := 0.0, -1
+	box = nil
+	count = 0
+	for i := 1; i < bvhSAHBins; i++ {
+		if bins[i-1].count > 0 {
+			if box == nil {
+				box = &bins[i-1].box
+			} else {
+				box = SurroundingBox(box, &bins[i-1].box)
+			}
+			count += bins[i-1].count
+		}
+		if count == 0 || rightCount[i] == 0 {
+			continue
+		}
+		cost := bvhTraversalCost + bvhIntersectionCost*(float64(count)*box.SurfaceArea()+float64(rightCount[i])*rightArea[i])/area
+		if bestSplit < 0 || cost < bestCost {
+			bestCost, bestSplit = cost, i
+		}
+	}
+
+	if n <= bvhMaxLeafSize && (bestSplit < 0 || bvhIntersectionCost*float64(n) <= bestCost) {
+		return 0, true
+	}
+
+	if bestSplit < 0 {
+		return n / 2, false
+	}
+
+	// the primitives are sorted along the axis so the bins are contiguous
+	mid := sort.Search(n, func(i int) bool {
+		return binIndex(&primitives[i]) >= bestSplit
+	})
+
+	return mid, false
+}
+
+// Hit checks the box first and, if hit, checks both children returning the closest hit
+func (n *BVHNode) Hit(r *Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	if !n.box.Hit(r, tMin, tMax) {
+		return false, nil
+	}
+
+	hitLeft, leftRecord := n.left.Hit(r, tMin, tMax)
+	if hitLeft {
+		tMax = leftRecord.T
+	}
+
+	if hitRight, rightRecord := n.right.Hit(r, tMin, tMax); hitRight {
+		return true, rightRecord
+	}
+
+	return hitLeft, leftRecord
+}
+
+// BoundingBox returns the box which contains both children
+func (n *BVHNode) BoundingBox() (bool, *AABB) {
+	return true, &n.box
+}
@@ -0,0 +1,101 @@
+package tracer
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// randomSpheres generates a world similar to the end result of the book (random small spheres on a big one)
+func randomSpheres() HitableList {
+	rnd := rand.New(rand.NewSource(2017))
+
+	world := HitableList{Sphere{Center: Point3{Y: -1000.0}, Radius: 1000, Material: Lambertian{Color{0.5, 0.5, 0.5}}}}
+	for a := -11; a < 11; a++ {
+		for b := -11; b < 11; b++ {
+			center := Point3{float64(a) + 0.9*rnd.Float64(), 0.2, float64(b) + 0.9*rnd.Float64()}
+			world = append(world, Sphere{Center: center, Radius: 0.2, Material: Dielectric{1.5}})
+		}
+	}
+	world = append(world,
+		Sphere{Center: Point3{0, 1, 0}, Radius: 1.0, Material: Dielectric{1.5}},
+		Sphere{Center: Point3{-4, 1, 0}, Radius: 1.0, Material: Lambertian{Color{0.4, 0.2, 0.1}}},
+		Sphere{Center: Point3{4, 1, 0}, Radius: 1.0, Material: Metal{Color{0.7, 0.6, 0.5}, 0}})
+
+	return world
+}
+
+func TestBVH_SameHitsAsList(t *testing.T) {
+	world := randomSpheres()
+
+	bvh := NewBVH(world)
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		origin := Point3{20.0*rnd.Float64() - 10.0, 5.0 * rnd.Float64(), 20.0*rnd.Float64() - 10.0}
+		r := &Ray{origin, RandomInUnitSphere(rnd), rnd}
+
+		hitList, hrList := world.Hit(r, 0.001, math.MaxFloat64)
+		hitBVH, hrBVH := bvh.Hit(r, 0.001, math.MaxFloat64)
+
+		if hitList != hitBVH {
+			t.Fatalf("ray %v: list hit %v but bvh hit %v", i, hitList, hitBVH)
+		}
+
+		if hitList && (hrList.T != hrBVH.T || hrList.P != hrBVH.P || hrList.Material != hrBVH.Material) {
+			t.Fatalf("ray %v: list hit %v but bvh hit %v", i, *hrList, *hrBVH)
+		}
+	}
+}
+
+func TestAABB_Hit(t *testing.T) {
+	box := AABB{Point3{-1, -1, -1}, Point3{1, 1, 1}}
+
+	var tests = []struct {
+		r        Ray
+		expected bool
+	}{
+		{Ray{Origin: Point3{Z: 5}, Direction: Vec3{Z: -1}}, true},
+		{Ray{Origin: Point3{Z: 5}, Direction: Vec3{Z: 1}}, false},
+		{Ray{Origin: Point3{X: 2, Z: 5}, Direction: Vec3{Z: -1}}, false},
+		{Ray{Origin: Point3{}, Direction: Vec3{X: 1, Y: 1}}, true},
+	}
+
+	for idx, test := range tests {
+		if hit := box.Hit(&test.r, 0.001, math.MaxFloat64); hit != test.expected {
+			t.Errorf("expected %v got %v instead [test %v]", test.expected, hit, idx)
+		}
+	}
+}
+
+func TestBuildBVH_Strategies(t *testing.T) {
+	world := randomSpheres()
+
+	for _, strategy := range []SplitStrategy{SplitMidpoint, SplitEqualCounts, SplitSAH} {
+		bvh, stats := BuildBVH(world, strategy)
+
+		primitives := 0
+		for size, count := range stats.LeafSizes {
+			primitives += size * count
+		}
+		if primitives != len(world) || stats.Primitives != len(world) {
+			t.Errorf("[%v] expected %v primitives in leaves got %v instead", strategy, len(world), primitives)
+		}
+		if stats.Nodes != stats.InteriorNodes+stats.Leaves {
+			t.Errorf("[%v] inconsistent node count %v", strategy, stats)
+		}
+
+		rnd := rand.New(rand.NewSource(1))
+		for i := 0; i < 1000; i++ {
+			origin := Point3{20.0*rnd.Float64() - 10.0, 5.0 * rnd.Float64(), 20.0*rnd.Float64() - 10.0}
+			r := &Ray{origin, RandomInUnitSphere(rnd), rnd}
+
+			hitList, hrList := world.Hit(r, 0.001, math.MaxFloat64)
+			hitBVH, hrBVH := bvh.Hit(r, 0.001, math.MaxFloat64)
+
+			if hitList != hitBVH || (hitList && hrList.T != hrBVH.T) {
+				t.Fatalf("[%v] ray %v: list and bvh disagree", strategy, i)
+			}
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package tracer
 
 import (
 	"math"
@@ -10,7 +10,7 @@ import (
 //  2. using (global) rand.Float() turns out to be a major slowdown when using multiple goroutines as
 // 		due to obvious reasons, it needs to be synchronized => can use a non synchronized version
 type Camera interface {
-	ray(rnd Rnd, u, v float64) *Ray
+	Ray(rnd Rnd, u, v float64) *Ray
 }
 
 type camera struct {
@@ -41,13 +41,13 @@ func NewCamera(lookFrom Point3, lookAt Point3, vup Vec3, vfov float64, aspect fl
 	return camera{origin, lowerLeftCorner, horizontal, vertical, u, v, aperture / 2.0}
 }
 
-// ray implements the main api of the Camera interface according to the book
-func (c camera) ray(rnd Rnd, u, v float64) *Ray {
+// Ray implements the main api of the Camera interface according to the book
+func (c camera) Ray(rnd Rnd, u, v float64) *Ray {
 	d := c.lowerLeftCorner.Translate(c.horizontal.Scale(u)).Translate(c.vertical.Scale(v)).Sub(c.origin)
 	origin := c.origin
 
 	if c.lensRadius > 0 {
-		rd := randomInUnitDisk(rnd).Scale(c.lensRadius)
+		rd := RandomInUnitDisk(rnd).Scale(c.lensRadius)
 		offset := c.u.Scale(rd.X).Add(c.v.Scale(rd.Y))
 		origin = origin.Translate(offset)
 		d = d.Sub(offset)
@@ -0,0 +1,22 @@
+// Package tracer is a ray tracer based on the Ray Tracing in One Weekend book.
+//
+// A scene is made of a Camera (see NewCamera), a world (any Hitable, for example a HitableList of Sphere whose
+// Material is Lambertian, Metal, Dielectric or DiffuseLight) and a Background. The world can also be loaded from
+// a scene file (LoadSceneFile) or from models (LoadModel, LoadGLTF).
+//
+//	camera := tracer.NewCamera(tracer.Point3{Z: 3}, tracer.Point3{}, tracer.Vec3{Y: 1}, 30, 2.0, 0, 3)
+//	world := tracer.HitableList{
+//		tracer.Sphere{Center: tracer.Point3{}, Radius: 0.5, Material: tracer.Lambertian{Albedo: tracer.Color{R: 0.8}}},
+//		tracer.Sphere{Center: tracer.Point3{Y: -100.5}, Radius: 100, Material: tracer.Metal{Albedo: tracer.White, Fuzz: 0.1}},
+//	}
+//	bvh, _ := tracer.BuildBVH(world, tracer.SplitSAH)
+//	scene := &tracer.Scene{Width: 400, Height: 200, RaysPerPixel: []int{100}, Camera: camera, World: bvh, Background: tracer.SkyBackground}
+//	fb, completed := scene.Render(runtime.NumCPU())
+//	<-completed
+//	pixels, _ := fb.Snapshot(nil)
+//
+// Scene.RenderContext is the cancellable version of Scene.Render which reports the progress with ProgressEvent.
+//
+// Custom objects, materials, textures and backgrounds can be provided by implementing the Hitable, Material,
+// Texture and Background interfaces.
+package tracer
@@ -0,0 +1,185 @@
+package tracer
+
+import (
+	"math"
+	"sort"
+)
+
+/***********************
+ * EnvironmentMap
+ ************************/
+// EnvironmentMap is a background defined by an image in equirectangular (latitude/longitude) projection.
+// The center of the image is in the -Z direction (before rotation) and the top row is straight up (+Y).
+//	rotation is the rotation (in radians) of the map around the Y axis
+//	intensity scales the radiance of the map
+// The map is importance sampled by luminance (see sample) so that small and bright areas (ex: the sun) can be
+// sampled explicitly from diffuse surfaces instead of relying on a scattered ray to randomly find them
+type EnvironmentMap struct {
+	image        *HDRImage
+	Rotation     float64
+	Intensity    float64
+	distribution *distribution2D
+}
+
+// NewEnvironmentMap creates an environment map from an image
+//	rotation is expressed in degrees (not radians)
+func NewEnvironmentMap(image *HDRImage, rotation float64, intensity float64) *EnvironmentMap {
+	// the luminance is weighted by sin(theta) to account for the stretching of the rows near the poles
+	weights := make([]float64, image.Width*image.Height)
+	for y := 0; y < image.Height; y++ {
+		sinTheta := math.Sin(math.Pi * (float64(y) + 0.5) / float64(image.Height))
+		for x := 0; x < image.Width; x++ {
+			weights[y*image.Width+x] = image.At(x, y).Luminance() * sinTheta
+		}
+	}
+
+	return &EnvironmentMap{
+		image:        image,
+		Rotation:     rotation * math.Pi / 180.0,
+		Intensity:    intensity,
+		distribution: newDistribution2D(weights, image.Width, image.Height),
+	}
+}
+
+// LoadEnvironmentMap loads an environment map from a Radiance (.hdr), PFM (.pfm) or regular (png, jpeg) image
+func LoadEnvironmentMap(path string, rotation float64, intensity float64) (*EnvironmentMap, error) {
+	image, err := LoadHDRImage(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewEnvironmentMap(image, rotation, intensity), nil
+}
+
+func (env *EnvironmentMap) Color(r *Ray) Color {
+	return env.radiance(r.Direction.Unit())
+}
+
+// radiance returns the color of the map in the (unit) direction
+func (env *EnvironmentMap) radiance(direction Vec3) Color {
+	u, v := directionToEquirectangular(rotateY(direction, -env.Rotation))
+	x, y := env.pixel(u, v)
+	return env.image.At(x, y).Scale(env.Intensity)
+}
+
+// pixel returns the coordinates of the pixel containing (u,v)
+func (env *EnvironmentMap) pixel(u, v float64) (int, int) {
+	x := int(u * float64(env.image.Width))
+	y := int(v * float64(env.image.Height))
+	if x >= env.image.Width {
+		x = env.image.Width - 1
+	}
+	if y >= env.image.Height {
+		y = env.image.Height - 1
+	}
+	return x, y
+}
+
+// sample chooses a direction with a probability proportional to the luminance of the map
+//	returns the (unit) direction, the radiance coming from it and the probability density (with respect to
+//	solid angle) of having chosen it. A pdf of 0 means that no direction could be sampled (black map)
+func (env *EnvironmentMap) sample(rnd Rnd) (Vec3, Color, float64) {
+	u, v, pdf := env.distribution.sample(sample2D(rnd))
+	if pdf == 0 {
+		return Vec3{}, Black, 0
+	}
+
+	sinTheta := math.Sin(v * math.Pi)
+	if sinTheta == 0 {
+		return Vec3{}, Black, 0
+	}
+
+	direction := rotateY(equirectangularToDirection(u, v), env.Rotation)
+	x, y := env.pixel(u, v)
+
+	// change of variable from (u,v) to solid angle: dw = 2 * pi * pi * sin(theta) du dv
+	return direction, env.image.At(x, y).Scale(env.Intensity), pdf / (2 * math.Pi * math.Pi * sinTheta)
+}
+
+// rotateY rotates the vector around the Y axis by angle (in radians)
+func rotateY(v Vec3, angle float64) Vec3 {
+	if angle == 0 {
+		return v
+	}
+	sin, cos := math.Sincos(angle)
+	return Vec3{cos*v.X + sin*v.Z, v.Y, -sin*v.X + cos*v.Z}
+}
+
+/***********************
+ * Distributions
+ ************************/
+// distribution1D is a piecewise constant distribution over [0,1) defined by (non negative) weights
+type distribution1D struct {
+	weights  []float64
+	cdf      []float64
+	integral float64
+}
+
+func newDistribution1D(weights []float64) *distribution1D {
+	n := len(weights)
+	cdf := make([]float64, n+1)
+	for i, w := range weights {
+		cdf[i+1] = cdf[i] + w/float64(n)
+	}
+
+	integral := cdf[n]
+	for i := 1; i <= n; i++ {
+		if integral > 0 {
+			cdf[i] /= integral
+		} else {
+			cdf[i] = float64(i) / float64(n)
+		}
+	}
+
+	return &distribution1D{weights, cdf, integral}
+}
+
+// sample maps a uniform value in [0,1) to a value in [0,1) distributed according to the weights
+//	returns the value, its probability density and the index of the segment it falls in
+func (d *distribution1D) sample(rnd float64) (float64, float64, int) {
+	n := len(d.weights)
+
+	// first index such that cdf[i+1] > rnd
+	i := sort.Search(n, func(i int) bool { return d.cdf[i+1] > rnd })
+	if i >= n {
+		i = n - 1
+	}
+
+	du := rnd - d.cdf[i]
+	if width := d.cdf[i+1] - d.cdf[i]; width > 0 {
+		du /= width
+	}
+
+	pdf := 1.0
+	if d.integral > 0 {
+		pdf = d.weights[i] / d.integral
+	}
+
+	return (float64(i) + du) / float64(n), pdf, i
+}
+
+// distribution2D is a piecewise constant distribution over [0,1)x[0,1) (sampling v with the marginal
+// distribution then u with the conditional distribution of the row)
+type distribution2D struct {
+	conditionals []*distribution1D
+	marginal     *distribution1D
+}
+
+func newDistribution2D(weights []float64, width, height int) *distribution2D {
+	conditionals := make([]*distribution1D, height)
+	rows := make([]float64, height)
+	for y := 0; y < height; y++ {
+		conditionals[y] = newDistribution1D(weights[y*width : (y+1)*width])
+		rows[y] = conditionals[y].integral
+	}
+	return &distribution2D{conditionals, newDistribution1D(rows)}
+}
+
+// sample returns (u,v) and the probability density (with respect to du dv)
+func (d *distribution2D) sample(rnd1, rnd2 float64) (float64, float64, float64) {
+	if d.marginal.integral == 0 {
+		return 0, 0, 0
+	}
+	v, pdfV, y := d.marginal.sample(rnd2)
+	u, pdfU, _ := d.conditionals[y].sample(rnd1)
+	return u, v, pdfU * pdfV
+}
@@ -0,0 +1,98 @@
+package tracer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestReadRadianceHDR_RLE(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n\n-Y 1 +X 8\n")
+	buf.Write([]byte{2, 2, 0, 8})
+	// each component: a run of 8 identical values
+	for _, value := range []byte{128, 64, 32, 129} {
+		buf.Write([]byte{128 + 8, value})
+	}
+
+	img, err := readRadianceHDR(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if img.Width != 8 || img.Height != 1 {
+		t.Fatalf("unexpected size %vx%v", img.Width, img.Height)
+	}
+
+	// exponent 129 => 2^(129-136) = 1/128
+	expected := Color{1.0, 0.5, 0.25}
+	for x := 0; x < 8; x++ {
+		if c := img.At(x, 0); c != expected {
+			t.Errorf("%v expected got %v instead [pixel %v]", expected, c, x)
+		}
+	}
+}
+
+func TestReadPFM(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("PF\n2 2\n-1.0\n")
+	// bottom row first
+	for _, v := range []float32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12} {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+
+	img, err := readPFM(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c := img.At(0, 1); c != (Color{1, 2, 3}) {
+		t.Errorf("unexpected bottom left pixel %v", c)
+	}
+	if c := img.At(1, 0); c != (Color{10, 11, 12}) {
+		t.Errorf("unexpected top right pixel %v", c)
+	}
+}
+
+// the importance sampled estimate of the power of the map must match the exact value (sum of the pixels weighted
+// by their solid angle)
+func TestEnvironmentMap_Sample(t *testing.T) {
+	width, height := 64, 32
+	img := &HDRImage{width, height, make([]Color, width*height)}
+	for k := range img.Pixels {
+		img.Pixels[k] = Color{0.1, 0.2, 0.3}
+	}
+	// small and very bright sun
+	img.Pixels[10*width+40] = Color{5000, 5000, 4000}
+
+	env := NewEnvironmentMap(img, 30, 1.0)
+	rnd := rand.New(rand.NewSource(1))
+
+	exact := 0.0
+	for y := 0; y < height; y++ {
+		solidAngle := 2 * math.Pi / float64(width) * (math.Cos(math.Pi*float64(y)/float64(height)) - math.Cos(math.Pi*float64(y+1)/float64(height)))
+		for x := 0; x < width; x++ {
+			exact += img.At(x, y).Luminance() * solidAngle
+		}
+	}
+
+	n := 100000
+	importance := 0.0
+	for i := 0; i < n; i++ {
+		direction, radiance, pdf := env.sample(rnd)
+		if pdf > 0 {
+			importance += radiance.Luminance() / pdf
+			if c := env.radiance(direction); math.Abs(c.Luminance()-radiance.Luminance()) > 1e-9 {
+				t.Fatalf("sampled radiance %v does not match radiance %v in the same direction", radiance, c)
+			}
+		}
+	}
+	importance /= float64(n)
+
+	if math.Abs(importance-exact)/exact > 0.01 {
+		t.Errorf("importance sampled estimate %v differs from exact value %v", importance, exact)
+	}
+}
@@ -0,0 +1,314 @@
+package tracer
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+/***********************
+ * OpenEXR
+ ************************/
+// EXRPixelType defines how the channels of an OpenEXR image are stored
+type EXRPixelType int
+
+const (
+	// EXRHalf stores each channel as a 16 bits floating point number (the most common format for compositing)
+	EXRHalf EXRPixelType = iota
+	// EXRFloat stores each channel as a 32 bits floating point number
+	EXRFloat
+)
+
+var exrPixelTypeNames = []string{"half", "float"}
+
+func (t EXRPixelType) String() string {
+	if t < 0 || int(t) >= len(exrPixelTypeNames) {
+		return fmt.Sprintf("EXRPixelType(%d)", int(t))
+	}
+	return exrPixelTypeNames[t]
+}
+
+// Set allows EXRPixelType to be used on the command line (flag)
+// Example: ray-tracing -o image.exr -exr-type float
+func (t *EXRPixelType) Set(value string) error {
+	for i, name := range exrPixelTypeNames {
+		if value == name {
+			*t = EXRPixelType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown exr pixel type [%v] (must be one of %v)", value, strings.Join(exrPixelTypeNames, ", "))
+}
+
+// EXRCompression defines how the scanlines of an OpenEXR image are compressed
+type EXRCompression int
+
+const (
+	// EXRZIPCompression compresses blocks of 16 scanlines with zlib (lossless)
+	EXRZIPCompression EXRCompression = iota
+	// EXRNoCompression stores the scanlines uncompressed
+	EXRNoCompression
+)
+
+var exrCompressionNames = []string{"zip", "none"}
+
+func (c EXRCompression) String() string {
+	if c < 0 || int(c) >= len(exrCompressionNames) {
+		return fmt.Sprintf("EXRCompression(%d)", int(c))
+	}
+	return exrCompressionNames[c]
+}
+
+// Set allows EXRCompression to be used on the command line (flag)
+// Example: ray-tracing -o image.exr -exr-compression none
+func (c *EXRCompression) Set(value string) error {
+	for i, name := range exrCompressionNames {
+		if value == name {
+			*c = EXRCompression(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown exr compression [%v] (must be one of %v)", value, strings.Join(exrCompressionNames, ", "))
+}
+
+// EXROptions defines how an OpenEXR image is written (the zero value is half with ZIP compression)
+type EXROptions struct {
+	PixelType   EXRPixelType
+	Compression EXRCompression
+}
+
+// values defined by the OpenEXR file format
+const (
+	exrMagic           = 20000630
+	exrVersion         = 2
+	exrPixelTypeHalf   = 1
+	exrPixelTypeFloat  = 2
+	exrCompressionNone = 0
+	exrCompressionZIP  = 3
+)
+
+// WriteEXR writes the image (linear colors) in OpenEXR format: a single part scanline image with R, G and B channels
+func WriteEXR(w io.Writer, img *HDRImage, options EXROptions) error {
+	if img.Width <= 0 || img.Height <= 0 {
+		return fmt.Errorf("invalid resolution %vx%v", img.Width, img.Height)
+	}
+
+	pixelType, bytesPerValue := int32(exrPixelTypeHalf), 2
+	if options.PixelType == EXRFloat {
+		pixelType, bytesPerValue = exrPixelTypeFloat, 4
+	}
+
+	compression, linesPerBlock := byte(exrCompressionZIP), 16
+	if options.Compression == EXRNoCompression {
+		compression, linesPerBlock = exrCompressionNone, 1
+	}
+
+	var header bytes.Buffer
+	le := binary.LittleEndian
+	writeInt32 := func(b *bytes.Buffer, v int32) {
+		var buf [4]byte
+		le.PutUint32(buf[:], uint32(v))
+		b.Write(buf[:])
+	}
+	writeFloat32 := func(b *bytes.Buffer, v float32) {
+		var buf [4]byte
+		le.PutUint32(buf[:], math.Float32bits(v))
+		b.Write(buf[:])
+	}
+	attribute := func(name, typ string, value []byte) {
+		header.WriteString(name)
+		header.WriteByte(0)
+		header.WriteString(typ)
+		header.WriteByte(0)
+		writeInt32(&header, int32(len(value)))
+		header.Write(value)
+	}
+
+	writeInt32(&header, exrMagic)
+	writeInt32(&header, exrVersion)
+
+	// channels must be sorted by name (B, G, R)
+	var channels bytes.Buffer
+	for _, name := range []string{"B", "G", "R"} {
+		channels.WriteString(name)
+		channels.WriteByte(0)
+		writeInt32(&channels, pixelType)
+		channels.Write([]byte{0, 0, 0, 0}) // pLinear + reserved
+		writeInt32(&channels, 1)           // xSampling
+		writeInt32(&channels, 1)           // ySampling
+	}
+	channels.WriteByte(0)
+	attribute("channels", "chlist", channels.Bytes())
+
+	attribute("compression", "compression", []byte{compression})
+
+	var window bytes.Buffer
+	writeInt32(&window, 0)
+	writeInt32(&window, 0)
+	writeInt32(&window, int32(img.Width-1))
+	writeInt32(&window, int32(img.Height-1))
+	attribute("dataWindow", "box2i", window.Bytes())
+	attribute("displayWindow", "box2i", window.Bytes())
+
+	attribute("lineOrder", "lineOrder", []byte{0}) // increasing y
+
+	var float bytes.Buffer
+	writeFloat32(&float, 1)
+	attribute("pixelAspectRatio", "float", float.Bytes())
+
+	var center bytes.Buffer
+	writeFloat32(&center, 0)
+	writeFloat32(&center, 0)
+	attribute("screenWindowCenter", "v2f", center.Bytes())
+	attribute("screenWindowWidth", "float", float.Bytes())
+
+	header.WriteByte(0)
+
+	// encode every block of scanlines (the offset table, which follows the header, points to each of them)
+	blockCount := (img.Height + linesPerBlock - 1) / linesPerBlock
+	blocks := make([][]byte, blockCount)
+	lineSize := img.Width * 3 * bytesPerValue
+	for b := range blocks {
+		y0 := b * linesPerBlock
+		y1 := minInt(y0+linesPerBlock, img.Height)
+
+		raw := make([]byte, 0, (y1-y0)*lineSize)
+		for y := y0; y < y1; y++ {
+			row := img.Pixels[y*img.Width : (y+1)*img.Width]
+			for c := 0; c < 3; c++ {
+				for _, p := range row {
+					v := p.B
+					switch c {
+					case 1:
+						v = p.G
+					case 2:
+						v = p.R
+					}
+					var buf [4]byte
+					if bytesPerValue == 2 {
+						le.PutUint16(buf[:], float32ToHalf(float32(v)))
+					} else {
+						le.PutUint32(buf[:], math.Float32bits(float32(v)))
+					}
+					raw = append(raw, buf[:bytesPerValue]...)
+				}
+			}
+		}
+
+		data := raw
+		if compression == exrCompressionZIP {
+			compressed, err := exrZIPCompress(raw)
+			if err != nil {
+				return err
+			}
+			// a block which does not compress is stored as is (the reader knows from the size)
+			if len(compressed) < len(raw) {
+				data = compressed
+			}
+		}
+
+		var block bytes.Buffer
+		writeInt32(&block, int32(y0))
+		writeInt32(&block, int32(len(data)))
+		block.Write(data)
+		blocks[b] = block.Bytes()
+	}
+
+	offset := uint64(header.Len() + 8*blockCount)
+	for _, block := range blocks {
+		var buf [8]byte
+		le.PutUint64(buf[:], offset)
+		header.Write(buf[:])
+		offset += uint64(len(block))
+	}
+
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return err
+	}
+	for _, block := range blocks {
+		if _, err := w.Write(block); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exrZIPCompress compresses a block the way OpenEXR does: the bytes are first reordered (even bytes then odd bytes)
+// and delta encoded (which helps zlib since neighboring values are close) before being compressed
+func exrZIPCompress(raw []byte) ([]byte, error) {
+	n := len(raw)
+	tmp := make([]byte, n)
+	half := (n + 1) / 2
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			tmp[i/2] = raw[i]
+		} else {
+			tmp[half+i/2] = raw[i]
+		}
+	}
+
+	for i := n - 1; i > 0; i-- {
+		tmp[i] = byte(int(tmp[i]) - int(tmp[i-1]) + 128)
+	}
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(tmp); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+// float32ToHalf converts a 32 bits floating point number into a 16 bits one (rounding to the nearest even value,
+// numbers too big become infinity)
+func float32ToHalf(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exponent := int(bits>>23) & 0xFF
+	mantissa := bits & 0x7FFFFF
+
+	switch {
+	case exponent == 0xFF:
+		// infinity or NaN (keeps a NaN a NaN)
+		if mantissa != 0 {
+			return sign | 0x7E00
+		}
+		return sign | 0x7C00
+
+	case exponent-127 > 15:
+		// overflow
+		return sign | 0x7C00
+
+	case exponent-127 >= -14:
+		// normal half (rounding may carry into the exponent which is fine, even up to infinity)
+		h := uint32(exponent-127+15)<<10 | mantissa>>13
+		rest := mantissa & 0x1FFF
+		if rest > 0x1000 || (rest == 0x1000 && h&1 == 1) {
+			h++
+		}
+		return sign | uint16(h)
+
+	case exponent-127 >= -25:
+		// subnormal half (the implicit leading 1 becomes explicit)
+		mantissa |= 0x800000
+		shift := uint(-14-(exponent-127)) + 13
+		h := mantissa >> shift
+		rest := mantissa & (1<<shift - 1)
+		halfway := uint32(1) << (shift - 1)
+		if rest > halfway || (rest == halfway && h&1 == 1) {
+			h++
+		}
+		return sign | uint16(h)
+	}
+
+	// underflow
+	return sign
+}
@@ -0,0 +1,210 @@
+package tracer
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+)
+
+// halfToFloat32 converts a 16 bits floating point number into a 32 bits one
+func halfToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exponent := int(h>>10) & 0x1F
+	mantissa := uint32(h & 0x3FF)
+
+	switch {
+	case exponent == 0x1F:
+		return math.Float32frombits(sign | 0x7F800000 | mantissa<<13)
+	case exponent == 0:
+		// subnormal
+		v := float32(mantissa) / (1 << 24)
+		if sign != 0 {
+			v = -v
+		}
+		return v
+	}
+	return math.Float32frombits(sign | uint32(exponent-15+127)<<23 | mantissa<<13)
+}
+
+// readEXR is a minimal reader (single part scanline images with B, G, R channels, uncompressed or ZIP) used to check
+// what WriteEXR produces
+func readEXR(data []byte) (*HDRImage, map[string][]byte, error) {
+	le := binary.LittleEndian
+	if le.Uint32(data) != exrMagic || le.Uint32(data[4:]) != exrVersion {
+		return nil, nil, fmt.Errorf("invalid magic/version")
+	}
+
+	cstring := func(b []byte) (string, []byte) {
+		i := bytes.IndexByte(b, 0)
+		return string(b[:i]), b[i+1:]
+	}
+
+	attributes := map[string][]byte{}
+	rest := data[8:]
+	for {
+		var name, typ string
+		name, rest = cstring(rest)
+		if name == "" {
+			break
+		}
+		typ, rest = cstring(rest)
+		size := int(le.Uint32(rest))
+		attributes[name+":"+typ] = rest[4 : 4+size]
+		rest = rest[4+size:]
+	}
+
+	window := attributes["dataWindow:box2i"]
+	width := int(int32(le.Uint32(window[8:]))) + 1
+	height := int(int32(le.Uint32(window[12:]))) + 1
+
+	bytesPerValue := 2
+	if le.Uint32(attributes["channels:chlist"][2:]) == exrPixelTypeFloat {
+		bytesPerValue = 4
+	}
+
+	linesPerBlock := 1
+	zip := attributes["compression:compression"][0] == exrCompressionZIP
+	if zip {
+		linesPerBlock = 16
+	}
+
+	img := &HDRImage{width, height, make([]Color, width*height)}
+	blockCount := (height + linesPerBlock - 1) / linesPerBlock
+	for b := 0; b < blockCount; b++ {
+		offset := le.Uint64(rest[b*8:])
+		block := data[offset:]
+		y0 := int(int32(le.Uint32(block)))
+		size := int(le.Uint32(block[4:]))
+		raw := block[8 : 8+size]
+
+		lines := linesPerBlock
+		if y0+lines > height {
+			lines = height - y0
+		}
+		expectedSize := lines * width * 3 * bytesPerValue
+
+		if zip && size < expectedSize {
+			zr, err := zlib.NewReader(bytes.NewReader(raw))
+			if err != nil {
+				return nil, nil, err
+			}
+			tmp, err := io.ReadAll(zr)
+			if err != nil {
+				return nil, nil, err
+			}
+			for i := 1; i < len(tmp); i++ {
+				tmp[i] = byte(int(tmp[i-1]) + int(tmp[i]) - 128)
+			}
+			raw = make([]byte, len(tmp))
+			half := (len(tmp) + 1) / 2
+			for i := range raw {
+				if i%2 == 0 {
+					raw[i] = tmp[i/2]
+				} else {
+					raw[i] = tmp[half+i/2]
+				}
+			}
+		}
+
+		if len(raw) != expectedSize {
+			return nil, nil, fmt.Errorf("block %v: %v bytes expected got %v instead", b, expectedSize, len(raw))
+		}
+
+		value := func(i int) float64 {
+			if bytesPerValue == 2 {
+				return float64(halfToFloat32(le.Uint16(raw[i*2:])))
+			}
+			return float64(math.Float32frombits(le.Uint32(raw[i*4:])))
+		}
+
+		for l := 0; l < lines; l++ {
+			for x := 0; x < width; x++ {
+				base := l * width * 3
+				img.Pixels[(y0+l)*width+x] = Color{R: value(base + 2*width + x), G: value(base + width + x), B: value(base + x)}
+			}
+		}
+	}
+
+	return img, attributes, nil
+}
+
+func TestWriteEXR_RoundTrip(t *testing.T) {
+	img := testHDRImage(37, 21)
+
+	var tests = []struct {
+		options   EXROptions
+		tolerance float64
+	}{
+		{EXROptions{EXRHalf, EXRNoCompression}, 1e-3},
+		{EXROptions{EXRHalf, EXRZIPCompression}, 1e-3},
+		{EXROptions{EXRFloat, EXRNoCompression}, 1e-6},
+		{EXROptions{EXRFloat, EXRZIPCompression}, 1e-6},
+	}
+
+	for idx, test := range tests {
+		var buf bytes.Buffer
+		if err := WriteEXR(&buf, img, test.options); err != nil {
+			t.Fatal(err)
+		}
+
+		read, attributes, err := readEXR(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%v [test %v]", err, idx)
+		}
+
+		for _, name := range []string{"channels:chlist", "compression:compression", "dataWindow:box2i", "displayWindow:box2i", "lineOrder:lineOrder", "pixelAspectRatio:float", "screenWindowCenter:v2f", "screenWindowWidth:float"} {
+			if _, ok := attributes[name]; !ok {
+				t.Errorf("missing required attribute %v [test %v]", name, idx)
+			}
+		}
+
+		if read.Width != img.Width || read.Height != img.Height {
+			t.Fatalf("%vx%v expected got %vx%v instead [test %v]", img.Width, img.Height, read.Width, read.Height, idx)
+		}
+
+		for k, c := range img.Pixels {
+			if e := relativeError(c, read.Pixels[k]); e > test.tolerance {
+				t.Errorf("%v expected got %v instead [pixel %v, test %v]", c, read.Pixels[k], k, idx)
+				break
+			}
+		}
+	}
+}
+
+func TestFloat32ToHalf(t *testing.T) {
+	var tests = []struct {
+		f        float32
+		expected uint16
+	}{
+		{0, 0x0000},
+		{float32(math.Copysign(0, -1)), 0x8000},
+		{1, 0x3C00},
+		{-2, 0xC000},
+		{0.5, 0x3800},
+		{65504, 0x7BFF},
+		{65520, 0x7C00}, // rounds up to infinity
+		{1e6, 0x7C00},
+		{float32(math.Inf(-1)), 0xFC00},
+		{6.103515625e-05, 0x0400},         // smallest normal
+		{5.960464477539063e-08, 0x0001},   // smallest subnormal
+		{2.98023223876953125e-08, 0x0000}, // half of the smallest subnormal rounds to even (0)
+		{1e-10, 0x0000},
+		{1.0009765625, 0x3C01},
+		{1.00048828125, 0x3C00}, // halfway rounds to even
+		{1.00146484375, 0x3C02}, // halfway rounds to even
+	}
+
+	for idx, test := range tests {
+		if h := float32ToHalf(test.f); h != test.expected {
+			t.Errorf("%04x expected got %04x instead [test %v]", test.expected, h, idx)
+		}
+	}
+
+	if h := float32ToHalf(float32(math.NaN())); h&0x7C00 != 0x7C00 || h&0x3FF == 0 {
+		t.Errorf("NaN expected got %04x instead", h)
+	}
+}
@@ -0,0 +1,149 @@
+package tracer
+
+import (
+	"fmt"
+	"math"
+)
+
+/***********************
+ * Filter
+ ************************/
+// Filter is a pixel reconstruction filter: every sample (ray) contributes to all the pixels whose center is within
+// Radius (in pixels, on both axes) of the sample, weighted by Evaluate (dx/dy is the offset from the center of the
+// pixel to the sample). The color of a pixel is the weighted average of the samples. Wide filters blur (less
+// aliasing) while filters with negative lobes (Mitchell, Lanczos) sharpen.
+type Filter interface {
+	Radius() float64
+	Evaluate(dx, dy float64) float64
+}
+
+// FilterNames lists the names accepted by ParseFilter
+var FilterNames = []string{"box", "tent", "gaussian", "mitchell", "lanczos"}
+
+// ParseFilter returns the filter matching the name (see FilterNames) with the given radius (in pixels) or the
+// default radius of the filter when radius <= 0 (box 0.5, tent 1, gaussian 1.5, mitchell 2 and lanczos 2)
+func ParseFilter(name string, radius float64) (Filter, error) {
+	or := func(defaultRadius float64) float64 {
+		if radius <= 0 {
+			return defaultRadius
+		}
+		return radius
+	}
+
+	switch name {
+	case "box":
+		return BoxFilter{R: or(0.5)}, nil
+	case "tent":
+		return TentFilter{R: or(1)}, nil
+	case "gaussian":
+		return GaussianFilter{R: or(1.5), Alpha: 2}, nil
+	case "mitchell":
+		return MitchellFilter{R: or(2), B: 1.0 / 3.0, C: 1.0 / 3.0}, nil
+	case "lanczos":
+		return LanczosFilter{R: or(2)}, nil
+	}
+	return nil, fmt.Errorf("unknown filter [%v] (must be one of %v)", name, FilterNames)
+}
+
+// BoxFilter weights equally every sample within R (with a radius of 0.5, each sample only contributes to the pixel
+// it was cast through which is the plain average of the book)
+type BoxFilter struct {
+	R float64
+}
+
+func (f BoxFilter) Radius() float64 {
+	return f.R
+}
+
+func (f BoxFilter) Evaluate(dx, dy float64) float64 {
+	if math.Abs(dx) < f.R && math.Abs(dy) < f.R {
+		return 1
+	}
+	return 0
+}
+
+// TentFilter weights the samples linearly decreasing from the center of the pixel to R
+type TentFilter struct {
+	R float64
+}
+
+func (f TentFilter) Radius() float64 {
+	return f.R
+}
+
+func (f TentFilter) Evaluate(dx, dy float64) float64 {
+	tent := func(d float64) float64 {
+		return math.Max(0, 1-math.Abs(d)/f.R)
+	}
+	return tent(dx) * tent(dy)
+}
+
+// GaussianFilter weights the samples with a gaussian (exp(-Alpha d²)) shifted so that it reaches 0 at R
+type GaussianFilter struct {
+	R, Alpha float64
+}
+
+func (f GaussianFilter) Radius() float64 {
+	return f.R
+}
+
+func (f GaussianFilter) Evaluate(dx, dy float64) float64 {
+	edge := math.Exp(-f.Alpha * f.R * f.R)
+	gaussian := func(d float64) float64 {
+		return math.Max(0, math.Exp(-f.Alpha*d*d)-edge)
+	}
+	return gaussian(dx) * gaussian(dy)
+}
+
+// MitchellFilter is the Mitchell-Netravali cubic filter (B = C = 1/3 is the recommended compromise between
+// blurring and ringing) stretched over R
+type MitchellFilter struct {
+	R, B, C float64
+}
+
+func (f MitchellFilter) Radius() float64 {
+	return f.R
+}
+
+func (f MitchellFilter) Evaluate(dx, dy float64) float64 {
+	return f.mitchell(2*dx/f.R) * f.mitchell(2*dy/f.R)
+}
+
+// mitchell is the 1D cubic defined over [-2,2]
+func (f MitchellFilter) mitchell(x float64) float64 {
+	b, c := f.B, f.C
+	x = math.Abs(x)
+	switch {
+	case x < 1:
+		return ((12-9*b-6*c)*x*x*x + (-18+12*b+6*c)*x*x + (6 - 2*b)) / 6
+	case x < 2:
+		return ((-b-6*c)*x*x*x + (6*b+30*c)*x*x + (-12*b-48*c)*x + (8*b + 24*c)) / 6
+	}
+	return 0
+}
+
+// LanczosFilter is the sinc function windowed by a wider sinc (sinc(d) sinc(d / R)) which is sharp but rings
+type LanczosFilter struct {
+	R float64
+}
+
+func (f LanczosFilter) Radius() float64 {
+	return f.R
+}
+
+func (f LanczosFilter) Evaluate(dx, dy float64) float64 {
+	lanczos := func(d float64) float64 {
+		if math.Abs(d) >= f.R {
+			return 0
+		}
+		return sinc(d) * sinc(d/f.R)
+	}
+	return lanczos(dx) * lanczos(dy)
+}
+
+func sinc(x float64) float64 {
+	if math.Abs(x) < 1e-5 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
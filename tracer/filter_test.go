@@ -0,0 +1,108 @@
+package tracer
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	var tests = []struct {
+		name     string
+		radius   float64
+		dx, dy   float64
+		expected float64
+	}{
+		{"box", 0, 0.49, -0.49, 1},
+		{"box", 0, 0.5, 0, 0},
+		{"box", 1.5, 1.2, 0.3, 1},
+		{"tent", 0, 0, 0, 1},
+		{"tent", 0, 0.5, 0, 0.5},
+		{"tent", 2, 1, 1, 0.25},
+		{"gaussian", 0, 0, 0, math.Pow(1-math.Exp(-2*1.5*1.5), 2)},
+		{"gaussian", 0, 1.5, 0, 0},
+		{"mitchell", 0, 0, 0, (8.0 / 9.0) * (8.0 / 9.0)},
+		{"mitchell", 0, 2, 0, 0},
+		{"lanczos", 0, 0, 0, 1},
+		{"lanczos", 0, 1, 0, 0},
+		{"lanczos", 3, 0, 2, 0},
+		{"lanczos", 3, 0, 3.5, 0},
+	}
+
+	for idx, test := range tests {
+		filter, err := ParseFilter(test.name, test.radius)
+		if err != nil {
+			t.Fatalf("unexpected error %v [test %v]", err, idx)
+		}
+		if v := filter.Evaluate(test.dx, test.dy); math.Abs(v-test.expected) > 1e-9 {
+			t.Errorf("%v expected got %v instead [test %v]", test.expected, v, idx)
+		}
+	}
+
+	// every filter is symmetric, 0 outside its radius and positive at the center
+	for _, name := range FilterNames {
+		filter, _ := ParseFilter(name, 0)
+		r := filter.Radius()
+		if filter.Evaluate(0, 0) <= 0 {
+			t.Errorf("%v: positive value expected at the center", name)
+		}
+		for _, d := range []float64{0.1, 0.3, 0.7, 1.2} {
+			if v1, v2 := filter.Evaluate(d, d/2), filter.Evaluate(-d, -d/2); v1 != v2 {
+				t.Errorf("%v: not symmetric %v != %v", name, v1, v2)
+			}
+		}
+		if v := filter.Evaluate(r, 0); v != 0 {
+			t.Errorf("%v: 0 expected at the radius got %v instead", name, v)
+		}
+		if v := filter.Evaluate(0, r+0.1); v != 0 {
+			t.Errorf("%v: 0 expected outside the radius got %v instead", name, v)
+		}
+	}
+
+	if _, err := ParseFilter("sinc", 0); err == nil {
+		t.Errorf("error expected for unknown filter")
+	}
+}
+
+func TestRenderContext_Filter(t *testing.T) {
+	for _, name := range FilterNames {
+		filter, _ := ParseFilter(name, 0)
+
+		render := func(parallelCount int, order TileOrder) *AccumulationBuffer {
+			scene := testScene(20, 10, 1, 2)
+			scene.Filter = filter
+			scene.TileSize = 4
+			scene.TileOrder = order
+			fb, done := scene.RenderContext(context.Background(), parallelCount, nil)
+			if err := <-done; err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			return fb.Accumulation()
+		}
+
+		// the samples splat across tiles but the result does not depend on the order of the tiles
+		expected := render(1, TileScan).Image()
+		actual := render(7, TileSpiral).Image()
+		for k := range expected.Pixels {
+			if expected.Pixels[k] != actual.Pixels[k] {
+				t.Errorf("%v: pixel %v %v expected got %v instead", name, k, expected.Pixels[k], actual.Pixels[k])
+				break
+			}
+		}
+
+		// a constant background is reconstructed exactly (the weights are normalized)
+		scene := testScene(12, 6, 4)
+		scene.World = HitableList{}
+		scene.Background = ConstantBackground{Color{0.5, 2, 0.25}}
+		scene.Filter = filter
+		scene.TileSize = 5
+		fb, done := scene.RenderContext(context.Background(), 3, nil)
+		<-done
+		for k, c := range fb.Accumulation().Image().Pixels {
+			if math.Abs(c.R-0.5) > 1e-9 || math.Abs(c.G-2) > 1e-9 || math.Abs(c.B-0.25) > 1e-9 {
+				t.Errorf("%v: pixel %v %v expected got %v instead", name, k, scene.Background, c)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package tracer
+
+import (
+	"sync/atomic"
+)
+
+// FrameBuffer holds the pixels (in packed RGB value) of the image being rendered. It is written by the goroutines
+// rendering the scene while viewers read it (for example to refresh a window) so every access is atomic (race free
+// under the go memory model). Viewers should use Snapshot to get a copy of the pixels which can be used freely.
+// The version is incremented every time a tile is rendered so that viewers can skip unchanged frames.
+// The pixels are derived, for display only, from the full precision result of the rendering (see Accumulation).
+type FrameBuffer struct {
+	width, height int
+	pixels        []uint32
+	version       uint64
+	accumulation  *AccumulationBuffer
+}
+
+// NewFrameBuffer creates a (black) frame buffer of width x height pixels
+func NewFrameBuffer(width, height int) *FrameBuffer {
+	return &FrameBuffer{width: width, height: height, pixels: make([]uint32, width*height)}
+}
+
+// Accumulation returns the buffer holding the full precision (linear) colors from which the pixels are derived
+// (nil when the frame buffer was not created by Scene.Render or Scene.RenderContext)
+func (fb *FrameBuffer) Accumulation() *AccumulationBuffer {
+	return fb.accumulation
+}
+
+// Width returns the width (in pixels) of the frame buffer
+func (fb *FrameBuffer) Width() int {
+	return fb.width
+}
+
+// Height returns the height (in pixels) of the frame buffer
+func (fb *FrameBuffer) Height() int {
+	return fb.height
+}
+
+// At returns the pixel x/y (in image coordinates: y = 0 is the top row)
+func (fb *FrameBuffer) At(x, y int) uint32 {
+	return atomic.LoadUint32(&fb.pixels[y*fb.width+x])
+}
+
+// Set changes the pixel x/y (in image coordinates: y = 0 is the top row)
+func (fb *FrameBuffer) Set(x, y int, value uint32) {
+	fb.store(y*fb.width+x, value)
+}
+
+// store changes the pixel at index k (row by row from the top)
+func (fb *FrameBuffer) store(k int, value uint32) {
+	atomic.StoreUint32(&fb.pixels[k], value)
+}
+
+// Publish increments the version to signal that pixels have changed
+func (fb *FrameBuffer) Publish() {
+	atomic.AddUint64(&fb.version, 1)
+}
+
+// Version returns the number of times pixels have been published
+func (fb *FrameBuffer) Version() uint64 {
+	return atomic.LoadUint64(&fb.version)
+}
+
+// Snapshot copies the pixels (row by row from the top) into dst (allocated when nil or too small) and returns it
+// along with the version of the frame buffer (the copy contains at least all the pixels published up to this version)
+func (fb *FrameBuffer) Snapshot(dst Pixels) (Pixels, uint64) {
+	version := fb.Version()
+
+	if len(dst) < len(fb.pixels) {
+		dst = make(Pixels, len(fb.pixels))
+	}
+	dst = dst[:len(fb.pixels)]
+
+	for k := range fb.pixels {
+		dst[k] = atomic.LoadUint32(&fb.pixels[k])
+	}
+
+	return dst, version
+}
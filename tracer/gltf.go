@@ -0,0 +1,837 @@
+package tracer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+/***********************
+ * glTF (json document)
+ ************************/
+// only the parts of the glTF 2.0 specification used by the loader are decoded
+type gltfDocument struct {
+	Scene       *int             `json:"scene"`
+	Scenes      []gltfScene      `json:"scenes"`
+	Nodes       []gltfNode       `json:"nodes"`
+	Meshes      []gltfMesh       `json:"meshes"`
+	Accessors   []gltfAccessor   `json:"accessors"`
+	BufferViews []gltfBufferView `json:"bufferViews"`
+	Buffers     []gltfBuffer     `json:"buffers"`
+	Materials   []gltfMaterial   `json:"materials"`
+	Textures    []gltfTexture    `json:"textures"`
+	Images      []gltfImage      `json:"images"`
+	Cameras     []gltfCamera     `json:"cameras"`
+}
+
+type gltfScene struct {
+	Nodes []int `json:"nodes"`
+}
+
+type gltfNode struct {
+	Children    []int     `json:"children"`
+	Mesh        *int      `json:"mesh"`
+	Camera      *int      `json:"camera"`
+	Matrix      []float64 `json:"matrix"`
+	Translation []float64 `json:"translation"`
+	Rotation    []float64 `json:"rotation"`
+	Scale       []float64 `json:"scale"`
+}
+
+type gltfMesh struct {
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices"`
+	Material   *int           `json:"material"`
+	Mode       *int           `json:"mode"`
+}
+
+type gltfAccessor struct {
+	BufferView    *int            `json:"bufferView"`
+	ByteOffset    int             `json:"byteOffset"`
+	ComponentType int             `json:"componentType"`
+	Normalized    bool            `json:"normalized"`
+	Count         int             `json:"count"`
+	Type          string          `json:"type"`
+	Sparse        json.RawMessage `json:"sparse"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride"`
+}
+
+type gltfBuffer struct {
+	URI        string `json:"uri"`
+	ByteLength int    `json:"byteLength"`
+}
+
+type gltfTextureInfo struct {
+	Index int `json:"index"`
+}
+
+type gltfMaterial struct {
+	PbrMetallicRoughness *struct {
+		BaseColorFactor  []float64        `json:"baseColorFactor"`
+		BaseColorTexture *gltfTextureInfo `json:"baseColorTexture"`
+		MetallicFactor   *float64         `json:"metallicFactor"`
+		RoughnessFactor  *float64         `json:"roughnessFactor"`
+	} `json:"pbrMetallicRoughness"`
+	EmissiveFactor []float64 `json:"emissiveFactor"`
+	AlphaMode      string    `json:"alphaMode"`
+	Extensions     struct {
+		Transmission *struct {
+			TransmissionFactor float64 `json:"transmissionFactor"`
+		} `json:"KHR_materials_transmission"`
+		IOR *struct {
+			IOR *float64 `json:"ior"`
+		} `json:"KHR_materials_ior"`
+		EmissiveStrength *struct {
+			EmissiveStrength float64 `json:"emissiveStrength"`
+		} `json:"KHR_materials_emissive_strength"`
+	} `json:"extensions"`
+}
+
+type gltfTexture struct {
+	Source *int `json:"source"`
+}
+
+type gltfImage struct {
+	URI        string `json:"uri"`
+	BufferView *int   `json:"bufferView"`
+}
+
+type gltfCamera struct {
+	Type        string `json:"type"`
+	Perspective *struct {
+		YFov float64 `json:"yfov"`
+	} `json:"perspective"`
+}
+
+/***********************
+ * glTF loader
+ ************************/
+// gltfLoader keeps track of the state while loading a glTF file (buffers and materials are shared by the meshes)
+type gltfLoader struct {
+	dir       string
+	doc       gltfDocument
+	glb       []byte // binary chunk of a .glb file
+	buffers   [][]byte
+	materials map[int]Material
+	textures  map[int]Texture
+	aspect    float64
+	camera    Camera
+	world     HitableList
+}
+
+// LoadGLTF loads a glTF 2.0 file (.gltf with external or embedded buffers, or binary .glb)
+//	the node hierarchy of the default scene is flattened: the transforms are applied to the vertices of the meshes
+//	the metallic-roughness materials are mapped onto the materials of this project:
+//		emissive => DiffuseLight (emissiveFactor x KHR_materials_emissive_strength)
+//		KHR_materials_transmission or alphaMode BLEND => Dielectric (KHR_materials_ior, 1.5 by default)
+//		metallic (metallicFactor >= 0.5) => Metal using the base color as albedo and the roughness as fuzz
+//		otherwise => Lambertian using the base color (factor x texture) as albedo
+//	the first perspective camera is converted using NewCamera with the aspect ratio provided (the image size wins
+//	over the aspect ratio of the file). Orthographic cameras are not supported and skipped. The camera returned is
+//	nil when the file does not define any perspective camera.
+func LoadGLTF(path string, aspect float64) (Camera, HitableList, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loader := &gltfLoader{
+		dir:       filepath.Dir(path),
+		materials: make(map[int]Material),
+		textures:  make(map[int]Texture),
+		aspect:    aspect,
+	}
+
+	if err := loader.load(data); err != nil {
+		return nil, nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	return loader.camera, loader.world, nil
+}
+
+const (
+	glbMagic     = 0x46546C67 // "glTF"
+	glbChunkJSON = 0x4E4F534A // "JSON"
+	glbChunkBIN  = 0x004E4942 // "BIN\0"
+)
+
+func (loader *gltfLoader) load(data []byte) error {
+	// binary glTF: 12 bytes header followed by chunks (json first, then optional binary buffer)
+	if len(data) >= 12 && binary.LittleEndian.Uint32(data) == glbMagic {
+		if version := binary.LittleEndian.Uint32(data[4:]); version != 2 {
+			return fmt.Errorf("unsupported glb version %v", version)
+		}
+		var jsonChunk []byte
+		for offset := 12; offset+8 <= len(data); {
+			length := int(binary.LittleEndian.Uint32(data[offset:]))
+			kind := binary.LittleEndian.Uint32(data[offset+4:])
+			offset += 8
+			if length < 0 || offset+length > len(data) {
+				return fmt.Errorf("truncated glb chunk")
+			}
+			switch kind {
+			case glbChunkJSON:
+				jsonChunk = data[offset : offset+length]
+			case glbChunkBIN:
+				loader.glb = data[offset : offset+length]
+			}
+			offset += length
+		}
+		if jsonChunk == nil {
+			return fmt.Errorf("missing json chunk in glb file")
+		}
+		data = jsonChunk
+	}
+
+	if err := json.Unmarshal(data, &loader.doc); err != nil {
+		return err
+	}
+
+	doc := &loader.doc
+
+	loader.buffers = make([][]byte, len(doc.Buffers))
+	for i, buffer := range doc.Buffers {
+		b, err := loader.readBuffer(buffer)
+		if err != nil {
+			return fmt.Errorf("buffers[%v]: %v", i, err)
+		}
+		loader.buffers[i] = b
+	}
+
+	// root nodes: the ones of the default scene (or the nodes which are not children when there is no scene)
+	var roots []int
+	switch {
+	case doc.Scene != nil && *doc.Scene >= 0 && *doc.Scene < len(doc.Scenes):
+		roots = doc.Scenes[*doc.Scene].Nodes
+	case len(doc.Scenes) > 0:
+		roots = doc.Scenes[0].Nodes
+	default:
+		isChild := make([]bool, len(doc.Nodes))
+		for _, node := range doc.Nodes {
+			for _, c := range node.Children {
+				if c >= 0 && c < len(isChild) {
+					isChild[c] = true
+				}
+			}
+		}
+		for i := range doc.Nodes {
+			if !isChild[i] {
+				roots = append(roots, i)
+			}
+		}
+	}
+
+	for _, n := range roots {
+		if err := loader.loadNode(n, identityMatrix, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readBuffer returns the content of a buffer (embedded as a data uri, external file or the glb binary chunk)
+func (loader *gltfLoader) readBuffer(buffer gltfBuffer) ([]byte, error) {
+	var b []byte
+	if buffer.URI == "" {
+		if loader.glb == nil {
+			return nil, fmt.Errorf("missing uri")
+		}
+		b = loader.glb
+	} else {
+		var err error
+		b, err = loader.readURI(buffer.URI)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(b) < buffer.ByteLength {
+		return nil, fmt.Errorf("buffer too small (%v bytes instead of %v)", len(b), buffer.ByteLength)
+	}
+	return b, nil
+}
+
+// readURI reads a data uri (base64) or a file relative to the glTF file
+func (loader *gltfLoader) readURI(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "data:") {
+		comma := strings.Index(uri, ",")
+		if comma < 0 || !strings.HasSuffix(uri[:comma], ";base64") {
+			return nil, fmt.Errorf("unsupported data uri (must be base64)")
+		}
+		return base64.StdEncoding.DecodeString(uri[comma+1:])
+	}
+
+	file, err := url.PathUnescape(uri)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(filepath.Join(loader.dir, filepath.FromSlash(file)))
+}
+
+// loadNode adds the meshes (and the camera) of the node and its children to the world
+func (loader *gltfLoader) loadNode(n int, parent gltfMatrix, depth int) error {
+	doc := &loader.doc
+	if n < 0 || n >= len(doc.Nodes) {
+		return fmt.Errorf("invalid node index %v", n)
+	}
+	if depth > len(doc.Nodes) {
+		return fmt.Errorf("nodes[%v]: cycle in the node hierarchy", n)
+	}
+
+	node := doc.Nodes[n]
+	transform, err := node.transform()
+	if err != nil {
+		return fmt.Errorf("nodes[%v]: %v", n, err)
+	}
+	transform = parent.mult(transform)
+
+	if node.Mesh != nil {
+		if err := loader.loadMesh(*node.Mesh, transform); err != nil {
+			return err
+		}
+	}
+
+	if node.Camera != nil && loader.camera == nil {
+		if err := loader.loadCamera(*node.Camera, transform); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range node.Children {
+		if err := loader.loadNode(c, transform, depth+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadCamera converts the camera (looking toward -Z in its own space with +Y up). Cameras which are not perspective
+// are skipped (loader.camera is left unset)
+func (loader *gltfLoader) loadCamera(c int, transform gltfMatrix) error {
+	doc := &loader.doc
+	if c < 0 || c >= len(doc.Cameras) {
+		return fmt.Errorf("invalid camera index %v", c)
+	}
+
+	camera := doc.Cameras[c]
+	if camera.Type != "perspective" {
+		return nil
+	}
+	if camera.Perspective == nil {
+		return fmt.Errorf("cameras[%v]: missing perspective", c)
+	}
+
+	lookFrom := transform.point(Point3{})
+	lookAt := lookFrom.Translate(transform.vector(Vec3{Z: -1}).Unit())
+	vup := transform.vector(Vec3{Y: 1}).Unit()
+	vfov := camera.Perspective.YFov * 180.0 / math.Pi
+
+	loader.camera = NewCamera(lookFrom, lookAt, vup, vfov, loader.aspect, 0.0, 1.0)
+
+	return nil
+}
+
+// loadMesh adds one Mesh per (triangle) primitive with its vertices transformed into world space
+func (loader *gltfLoader) loadMesh(m int, transform gltfMatrix) error {
+	doc := &loader.doc
+	if m < 0 || m >= len(doc.Meshes) {
+		return fmt.Errorf("invalid mesh index %v", m)
+	}
+
+	normalMatrix := transform.normalMatrix()
+	// a transform with a negative determinant (mirror) reverses the winding of the triangles
+	mirror := transform.determinant() < 0
+
+	for p, primitive := range doc.Meshes[m].Primitives {
+		location := fmt.Sprintf("meshes[%v].primitives[%v]", m, p)
+
+		mode := 4
+		if primitive.Mode != nil {
+			mode = *primitive.Mode
+		}
+		// points and lines are not rendered
+		if mode < 4 || mode > 6 {
+			continue
+		}
+
+		position, ok := primitive.Attributes["POSITION"]
+		if !ok {
+			return fmt.Errorf("%v: missing POSITION attribute", location)
+		}
+		positions, err := loader.readAccessor(position, "VEC3")
+		if err != nil {
+			return fmt.Errorf("%v: POSITION: %v", location, err)
+		}
+		vertices := make([]Point3, len(positions)/3)
+		for i := range vertices {
+			vertices[i] = transform.point(Point3{positions[3*i], positions[3*i+1], positions[3*i+2]})
+		}
+
+		var normals []Vec3
+		if normal, ok := primitive.Attributes["NORMAL"]; ok {
+			values, err := loader.readAccessor(normal, "VEC3")
+			if err != nil {
+				return fmt.Errorf("%v: NORMAL: %v", location, err)
+			}
+			if len(values) != len(positions) {
+				return fmt.Errorf("%v: NORMAL: count does not match POSITION", location)
+			}
+			normals = make([]Vec3, len(vertices))
+			for i := range normals {
+				normals[i] = normalMatrix.vector(Vec3{values[3*i], values[3*i+1], values[3*i+2]}).Unit()
+				if mirror {
+					normals[i] = normals[i].Negate()
+				}
+			}
+		}
+
+		var uvs []UV
+		if texcoord, ok := primitive.Attributes["TEXCOORD_0"]; ok {
+			values, err := loader.readAccessor(texcoord, "VEC2")
+			if err != nil {
+				return fmt.Errorf("%v: TEXCOORD_0: %v", location, err)
+			}
+			if len(values)/2 != len(vertices) {
+				return fmt.Errorf("%v: TEXCOORD_0: count does not match POSITION", location)
+			}
+			uvs = make([]UV, len(vertices))
+			for i := range uvs {
+				// glTF uv (0,0) is the top left corner of the image (bottom left for ImageTexture)
+				uvs[i] = UV{values[2*i], 1 - values[2*i+1]}
+			}
+		}
+
+		var elements []int
+		if primitive.Indices != nil {
+			values, err := loader.readAccessor(*primitive.Indices, "SCALAR")
+			if err != nil {
+				return fmt.Errorf("%v: indices: %v", location, err)
+			}
+			elements = make([]int, len(values))
+			for i, v := range values {
+				elements[i] = int(v)
+				if elements[i] < 0 || elements[i] >= len(vertices) {
+					return fmt.Errorf("%v: indices: vertex index %v out of range (%v vertices)", location, elements[i], len(vertices))
+				}
+			}
+		} else {
+			elements = make([]int, len(vertices))
+			for i := range elements {
+				elements[i] = i
+			}
+		}
+
+		indices := gltfTriangles(mode, elements)
+		if len(indices) == 0 {
+			continue
+		}
+		if mirror {
+			for t := 0; t < len(indices); t += 3 {
+				indices[t+1], indices[t+2] = indices[t+2], indices[t+1]
+			}
+		}
+
+		var material Material = defaultModelMaterial
+		if primitive.Material != nil {
+			material, err = loader.material(*primitive.Material)
+			if err != nil {
+				return err
+			}
+		}
+
+		loader.world = append(loader.world, NewMesh(vertices, normals, uvs, indices, material))
+	}
+
+	return nil
+}
+
+// gltfTriangles converts the elements of a primitive into a list of triangles (3 indices each)
+//	mode is 4 (triangles), 5 (triangle strip) or 6 (triangle fan)
+func gltfTriangles(mode int, elements []int) []int {
+	var indices []int
+	switch mode {
+	case 5:
+		for i := 2; i < len(elements); i++ {
+			if i%2 == 0 {
+				indices = append(indices, elements[i-2], elements[i-1], elements[i])
+			} else {
+				indices = append(indices, elements[i-1], elements[i-2], elements[i])
+			}
+		}
+	case 6:
+		for i := 2; i < len(elements); i++ {
+			indices = append(indices, elements[0], elements[i-1], elements[i])
+		}
+	default:
+		indices = elements[:len(elements)-len(elements)%3]
+	}
+	return indices
+}
+
+// gltfComponentSizes is the size (in bytes) of each component type
+var gltfComponentSizes = map[int]int{5120: 1, 5121: 1, 5122: 2, 5123: 2, 5125: 4, 5126: 4}
+
+// gltfTypeSizes is the number of components of each accessor type
+var gltfTypeSizes = map[string]int{"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4, "MAT2": 4, "MAT3": 9, "MAT4": 16}
+
+// readAccessor reads all the components of the accessor as float64 (normalized integers are converted to [0,1]
+// or [-1,1]). kind is the type expected for the accessor.
+func (loader *gltfLoader) readAccessor(a int, kind string) ([]float64, error) {
+	doc := &loader.doc
+	if a < 0 || a >= len(doc.Accessors) {
+		return nil, fmt.Errorf("invalid accessor index %v", a)
+	}
+
+	accessor := doc.Accessors[a]
+	if accessor.Type != kind {
+		return nil, fmt.Errorf("accessors[%v]: type %v instead of %v", a, accessor.Type, kind)
+	}
+	if len(accessor.Sparse) > 0 {
+		return nil, fmt.Errorf("accessors[%v]: sparse accessors are not supported", a)
+	}
+	componentSize, ok := gltfComponentSizes[accessor.ComponentType]
+	if !ok {
+		return nil, fmt.Errorf("accessors[%v]: invalid component type %v", a, accessor.ComponentType)
+	}
+
+	if accessor.Count < 0 || accessor.ByteOffset < 0 {
+		return nil, fmt.Errorf("accessors[%v]: invalid count %v or byte offset %v", a, accessor.Count, accessor.ByteOffset)
+	}
+
+	components := gltfTypeSizes[kind]
+	values := make([]float64, accessor.Count*components)
+
+	// no buffer view means all zeros
+	if accessor.BufferView == nil {
+		return values, nil
+	}
+
+	v := *accessor.BufferView
+	if v < 0 || v >= len(doc.BufferViews) {
+		return nil, fmt.Errorf("accessors[%v]: invalid buffer view index %v", a, v)
+	}
+	view := doc.BufferViews[v]
+	if view.Buffer < 0 || view.Buffer >= len(loader.buffers) {
+		return nil, fmt.Errorf("bufferViews[%v]: invalid buffer index %v", v, view.Buffer)
+	}
+	buffer := loader.buffers[view.Buffer]
+	if view.ByteOffset < 0 || view.ByteLength < 0 || view.ByteOffset+view.ByteLength > len(buffer) {
+		return nil, fmt.Errorf("bufferViews[%v]: out of the bounds of the buffer", v)
+	}
+	data := buffer[view.ByteOffset : view.ByteOffset+view.ByteLength]
+
+	elementSize := componentSize * components
+	stride := view.ByteStride
+	if stride == 0 {
+		stride = elementSize
+	}
+	if stride < elementSize {
+		return nil, fmt.Errorf("bufferViews[%v]: invalid byte stride %v", v, view.ByteStride)
+	}
+	if accessor.Count > 0 && accessor.ByteOffset+(accessor.Count-1)*stride+elementSize > len(data) {
+		return nil, fmt.Errorf("accessors[%v]: out of the bounds of the buffer view", a)
+	}
+
+	for i := 0; i < accessor.Count; i++ {
+		offset := accessor.ByteOffset + i*stride
+		for c := 0; c < components; c++ {
+			values[i*components+c] = gltfComponent(data[offset+c*componentSize:], accessor.ComponentType, accessor.Normalized)
+		}
+	}
+
+	return values, nil
+}
+
+// gltfComponent decodes a single (little endian) component
+func gltfComponent(b []byte, componentType int, normalized bool) float64 {
+	switch componentType {
+	case 5120:
+		v := float64(int8(b[0]))
+		if normalized {
+			return math.Max(v/127.0, -1)
+		}
+		return v
+	case 5121:
+		v := float64(b[0])
+		if normalized {
+			return v / 255.0
+		}
+		return v
+	case 5122:
+		v := float64(int16(binary.LittleEndian.Uint16(b)))
+		if normalized {
+			return math.Max(v/32767.0, -1)
+		}
+		return v
+	case 5123:
+		v := float64(binary.LittleEndian.Uint16(b))
+		if normalized {
+			return v / 65535.0
+		}
+		return v
+	case 5125:
+		return float64(binary.LittleEndian.Uint32(b))
+	default:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+}
+
+// material converts (and caches) a glTF material (see LoadGLTF for the mapping)
+func (loader *gltfLoader) material(m int) (Material, error) {
+	if material, ok := loader.materials[m]; ok {
+		return material, nil
+	}
+
+	doc := &loader.doc
+	if m < 0 || m >= len(doc.Materials) {
+		return nil, fmt.Errorf("invalid material index %v", m)
+	}
+	def := doc.Materials[m]
+
+	baseColor := White
+	metallic, roughness := 1.0, 1.0
+	var texture *gltfTextureInfo
+	if pbr := def.PbrMetallicRoughness; pbr != nil {
+		if len(pbr.BaseColorFactor) >= 3 {
+			baseColor = Color{pbr.BaseColorFactor[0], pbr.BaseColorFactor[1], pbr.BaseColorFactor[2]}
+		}
+		if pbr.MetallicFactor != nil {
+			metallic = *pbr.MetallicFactor
+		}
+		if pbr.RoughnessFactor != nil {
+			roughness = *pbr.RoughnessFactor
+		}
+		texture = pbr.BaseColorTexture
+	}
+
+	var albedo Texture = baseColor
+	if texture != nil {
+		t, err := loader.texture(texture.Index)
+		if err != nil {
+			return nil, fmt.Errorf("materials[%v]: %v", m, err)
+		}
+		albedo = t
+		if baseColor != White {
+			albedo = scaledTexture{t, baseColor}
+		}
+	}
+
+	var emissive Color
+	if len(def.EmissiveFactor) >= 3 {
+		emissive = Color{def.EmissiveFactor[0], def.EmissiveFactor[1], def.EmissiveFactor[2]}
+		if def.Extensions.EmissiveStrength != nil {
+			emissive = emissive.Scale(def.Extensions.EmissiveStrength.EmissiveStrength)
+		}
+	}
+
+	ior := 1.5
+	if def.Extensions.IOR != nil && def.Extensions.IOR.IOR != nil {
+		ior = *def.Extensions.IOR.IOR
+	}
+
+	var material Material
+	switch {
+	case emissive.Luminance() > 0:
+		material = DiffuseLight{emissive}
+	case (def.Extensions.Transmission != nil && def.Extensions.Transmission.TransmissionFactor > 0) || def.AlphaMode == "BLEND":
+		material = Dielectric{ior}
+	case metallic >= 0.5:
+		material = Metal{albedo, math.Min(roughness, roughMetalFuzz)}
+	default:
+		material = Lambertian{albedo}
+	}
+
+	loader.materials[m] = material
+	return material, nil
+}
+
+// texture loads (and caches) the image of a texture (from a file, a data uri or a buffer view)
+func (loader *gltfLoader) texture(t int) (Texture, error) {
+	if texture, ok := loader.textures[t]; ok {
+		return texture, nil
+	}
+
+	doc := &loader.doc
+	if t < 0 || t >= len(doc.Textures) {
+		return nil, fmt.Errorf("invalid texture index %v", t)
+	}
+	source := doc.Textures[t].Source
+	if source == nil || *source < 0 || *source >= len(doc.Images) {
+		return nil, fmt.Errorf("textures[%v]: invalid image source", t)
+	}
+	img := doc.Images[*source]
+
+	var data []byte
+	switch {
+	case img.BufferView != nil:
+		v := *img.BufferView
+		if v < 0 || v >= len(doc.BufferViews) {
+			return nil, fmt.Errorf("images[%v]: invalid buffer view index %v", *source, v)
+		}
+		view := doc.BufferViews[v]
+		if view.Buffer < 0 || view.Buffer >= len(loader.buffers) || view.ByteOffset < 0 || view.ByteLength < 0 ||
+			view.ByteOffset+view.ByteLength > len(loader.buffers[view.Buffer]) {
+			return nil, fmt.Errorf("bufferViews[%v]: out of the bounds of the buffer", v)
+		}
+		data = loader.buffers[view.Buffer][view.ByteOffset : view.ByteOffset+view.ByteLength]
+	case img.URI != "":
+		var err error
+		data, err = loader.readURI(img.URI)
+		if err != nil {
+			return nil, fmt.Errorf("images[%v]: %v", *source, err)
+		}
+	default:
+		return nil, fmt.Errorf("images[%v]: missing uri or buffer view", *source)
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("images[%v]: %v", *source, err)
+	}
+
+	texture := NewImageTexture(NewHDRImageFromImage(decoded))
+	loader.textures[t] = texture
+	return texture, nil
+}
+
+// scaledTexture multiplies a texture by a color (ex: glTF base color factor x base color texture)
+type scaledTexture struct {
+	texture Texture
+	factor  Color
+}
+
+func (st scaledTexture) Value(u, v float64, p Point3) Color {
+	return st.texture.Value(u, v, p).Mult(st.factor)
+}
+
+/***********************
+ * glTF transforms
+ ************************/
+// gltfMatrix is a 4x4 matrix stored in column major order (like in glTF)
+type gltfMatrix [16]float64
+
+var identityMatrix = gltfMatrix{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+
+// transform returns the local transform of the node (matrix or translation x rotation x scale)
+func (node *gltfNode) transform() (gltfMatrix, error) {
+	if node.Matrix != nil {
+		if len(node.Matrix) != 16 {
+			return identityMatrix, fmt.Errorf("matrix must have 16 values")
+		}
+		var m gltfMatrix
+		copy(m[:], node.Matrix)
+		return m, nil
+	}
+
+	t := []float64{0, 0, 0}
+	r := []float64{0, 0, 0, 1}
+	s := []float64{1, 1, 1}
+	if node.Translation != nil {
+		t = node.Translation
+	}
+	if node.Rotation != nil {
+		r = node.Rotation
+	}
+	if node.Scale != nil {
+		s = node.Scale
+	}
+	if len(t) != 3 || len(r) != 4 || len(s) != 3 {
+		return identityMatrix, fmt.Errorf("invalid translation, rotation or scale")
+	}
+
+	// rotation is a unit quaternion (x, y, z, w)
+	x, y, z, w := r[0], r[1], r[2], r[3]
+	return gltfMatrix{
+		(1 - 2*(y*y+z*z)) * s[0], 2 * (x*y + z*w) * s[0], 2 * (x*z - y*w) * s[0], 0,
+		2 * (x*y - z*w) * s[1], (1 - 2*(x*x+z*z)) * s[1], 2 * (y*z + x*w) * s[1], 0,
+		2 * (x*z + y*w) * s[2], 2 * (y*z - x*w) * s[2], (1 - 2*(x*x+y*y)) * s[2], 0,
+		t[0], t[1], t[2], 1,
+	}, nil
+}
+
+// at returns the element at the given row and column
+func (m gltfMatrix) at(row, col int) float64 {
+	return m[col*4+row]
+}
+
+// mult returns m x m2
+func (m gltfMatrix) mult(m2 gltfMatrix) gltfMatrix {
+	var res gltfMatrix
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			sum := 0.0
+			for k := 0; k < 4; k++ {
+				sum += m.at(row, k) * m2.at(k, col)
+			}
+			res[col*4+row] = sum
+		}
+	}
+	return res
+}
+
+// point transforms a point (translation applies)
+func (m gltfMatrix) point(p Point3) Point3 {
+	return Point3{
+		m.at(0, 0)*p.X + m.at(0, 1)*p.Y + m.at(0, 2)*p.Z + m.at(0, 3),
+		m.at(1, 0)*p.X + m.at(1, 1)*p.Y + m.at(1, 2)*p.Z + m.at(1, 3),
+		m.at(2, 0)*p.X + m.at(2, 1)*p.Y + m.at(2, 2)*p.Z + m.at(2, 3),
+	}
+}
+
+// vector transforms a vector (translation does not apply)
+func (m gltfMatrix) vector(v Vec3) Vec3 {
+	return Vec3{
+		m.at(0, 0)*v.X + m.at(0, 1)*v.Y + m.at(0, 2)*v.Z,
+		m.at(1, 0)*v.X + m.at(1, 1)*v.Y + m.at(1, 2)*v.Z,
+		m.at(2, 0)*v.X + m.at(2, 1)*v.Y + m.at(2, 2)*v.Z,
+	}
+}
+
+// determinant returns the determinant of the upper 3x3 part of the matrix
+func (m gltfMatrix) determinant() float64 {
+	return m.at(0, 0)*(m.at(1, 1)*m.at(2, 2)-m.at(1, 2)*m.at(2, 1)) -
+		m.at(0, 1)*(m.at(1, 0)*m.at(2, 2)-m.at(1, 2)*m.at(2, 0)) +
+		m.at(0, 2)*(m.at(1, 0)*m.at(2, 1)-m.at(1, 1)*m.at(2, 0))
+}
+
+// normalMatrix returns the matrix used to transform normals: the cofactor matrix of the upper 3x3 part (which is
+// the inverse transpose scaled by the determinant, the scale being irrelevant since normals are normalized)
+func (m gltfMatrix) normalMatrix() gltfMatrix {
+	cofactor := func(r0, r1, c0, c1 int) float64 {
+		return m.at(r0, c0)*m.at(r1, c1) - m.at(r0, c1)*m.at(r1, c0)
+	}
+	var res gltfMatrix
+	set := func(row, col int, v float64) {
+		res[col*4+row] = v
+	}
+	set(0, 0, cofactor(1, 2, 1, 2))
+	set(0, 1, -cofactor(1, 2, 0, 2))
+	set(0, 2, cofactor(1, 2, 0, 1))
+	set(1, 0, -cofactor(0, 2, 1, 2))
+	set(1, 1, cofactor(0, 2, 0, 2))
+	set(1, 2, -cofactor(0, 2, 0, 1))
+	set(2, 0, cofactor(0, 1, 1, 2))
+	set(2, 1, -cofactor(0, 1, 0, 2))
+	set(2, 2, cofactor(0, 1, 0, 1))
+	set(3, 3, 1)
+	return res
+}
@@ -0,0 +1,224 @@
+package tracer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gltfTestBuffer returns a buffer with 3 positions (float) followed by 3 indices (unsigned short)
+func gltfTestBuffer() []byte {
+	buf := &bytes.Buffer{}
+	for _, v := range []float32{0, 0, 0, 1, 0, 0, 0, 1, 0} {
+		binary.Write(buf, binary.LittleEndian, v)
+	}
+	binary.Write(buf, binary.LittleEndian, []uint16{0, 1, 2})
+	return buf.Bytes()
+}
+
+// gltfTestDocument returns a document with a triangle (translated by 2 along X and rotated by 90 degrees around Y)
+// and a camera at (0,0,5) looking toward -Z
+func gltfTestDocument(buffer string) string {
+	return fmt.Sprintf(`{
+  "asset": {"version": "2.0"},
+  "scene": 0,
+  "scenes": [{"nodes": [0, 2]}],
+  "nodes": [
+    {"translation": [2, 0, 0], "children": [1]},
+    {"rotation": [0, %v, 0, %v], "mesh": 0},
+    {"translation": [0, 0, 5], "camera": 0}
+  ],
+  "cameras": [{"type": "perspective", "perspective": {"yfov": 0.8, "znear": 0.1}}],
+  "meshes": [{"primitives": [{"attributes": {"POSITION": 0}, "indices": 1, "material": 0}]}],
+  "materials": [{"pbrMetallicRoughness": {"baseColorFactor": [0.9, 0.8, 0.7, 1], "metallicFactor": 1, "roughnessFactor": 0.2}}],
+  "accessors": [
+    {"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3"},
+    {"bufferView": 1, "componentType": 5123, "count": 3, "type": "SCALAR"}
+  ],
+  "bufferViews": [
+    {"buffer": 0, "byteOffset": 0, "byteLength": 36},
+    {"buffer": 0, "byteOffset": 36, "byteLength": 6}
+  ],
+  "buffers": [{%v"byteLength": 42}]
+}`, math.Sin(math.Pi/4), math.Cos(math.Pi/4), buffer)
+}
+
+func TestLoadGLTF(t *testing.T) {
+	// glb: same document with the buffer in the binary chunk
+	doc := []byte(gltfTestDocument(""))
+	for len(doc)%4 != 0 {
+		doc = append(doc, ' ')
+	}
+	bin := gltfTestBuffer()
+	for len(bin)%4 != 0 {
+		bin = append(bin, 0)
+	}
+	glb := &bytes.Buffer{}
+	binary.Write(glb, binary.LittleEndian, []uint32{glbMagic, 2, uint32(12 + 8 + len(doc) + 8 + len(bin))})
+	binary.Write(glb, binary.LittleEndian, []uint32{uint32(len(doc)), glbChunkJSON})
+	glb.Write(doc)
+	binary.Write(glb, binary.LittleEndian, []uint32{uint32(len(bin)), glbChunkBIN})
+	glb.Write(bin)
+
+	dir := writeTestFiles(t, map[string]string{
+		"embedded.gltf":     gltfTestDocument(`"uri": "data:application/octet-stream;base64,` + base64.StdEncoding.EncodeToString(gltfTestBuffer()) + `", `),
+		"external.gltf":     gltfTestDocument(`"uri": "triangle%20data.bin", `),
+		"triangle data.bin": string(gltfTestBuffer()),
+		"binary.glb":        glb.String(),
+	})
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"embedded.gltf", "external.gltf", "binary.glb"} {
+		camera, world, err := LoadGLTF(filepath.Join(dir, name), 2.0)
+		if err != nil {
+			t.Fatalf("%v [test %v]", err, name)
+		}
+
+		if len(world) != 1 {
+			t.Fatalf("%v != 1 meshes [test %v]", len(world), name)
+		}
+		mesh := world[0].(*Mesh)
+
+		// rotation of 90 degrees around Y maps X to -Z
+		expected := []Point3{{2, 0, 0}, {2, 0, -1}, {2, 1, 0}}
+		for i, p := range expected {
+			v := mesh.vertices[i]
+			if !floatEquals(v.X, p.X) || !floatEquals(v.Y, p.Y) || !floatEquals(v.Z, p.Z) {
+				t.Errorf("vertex %v: %v != %v [test %v]", i, v, p, name)
+			}
+		}
+
+		metal, ok := mesh.material.(Metal)
+		if !ok {
+			t.Fatalf("%T is not a Metal [test %v]", mesh.material, name)
+		}
+		if metal.Albedo != (Color{0.9, 0.8, 0.7}) || !floatEquals(metal.Fuzz, 0.2) {
+			t.Errorf("%v != {0.9 0.8 0.7} 0.2 [test %v]", metal, name)
+		}
+
+		if camera == nil {
+			t.Fatalf("missing camera [test %v]", name)
+		}
+		r := camera.Ray(nil, 0.5, 0.5)
+		d := r.Direction.Unit()
+		if !floatEquals(r.Origin.Z, 5) || !floatEquals(d.X, 0) || !floatEquals(d.Y, 0) || !floatEquals(d.Z, -1) {
+			t.Errorf("camera ray %v %v [test %v]", r.Origin, d, name)
+		}
+	}
+}
+
+func TestLoadGLTF_MetalFuzz(t *testing.T) {
+	embedded := `"uri": "data:application/octet-stream;base64,` + base64.StdEncoding.EncodeToString(gltfTestBuffer()) + `", `
+	material := `{"pbrMetallicRoughness": {"baseColorFactor": [0.9, 0.8, 0.7, 1], "metallicFactor": 1, "roughnessFactor": 0.2}}`
+
+	// the spec defaults (metallic = roughness = 1) are a fully rough metal, not a mirror
+	tests := []struct {
+		material string
+		fuzz     float64
+	}{
+		{`{}`, roughMetalFuzz},
+		{`{"pbrMetallicRoughness": {"roughnessFactor": 1}}`, roughMetalFuzz},
+		{`{"pbrMetallicRoughness": {"metallicFactor": 1, "roughnessFactor": 0.5}}`, 0.5},
+	}
+
+	for idx, test := range tests {
+		dir := writeTestFiles(t, map[string]string{
+			"material.gltf": strings.Replace(gltfTestDocument(embedded), material, test.material, 1),
+		})
+		defer os.RemoveAll(dir)
+
+		_, world, err := LoadGLTF(filepath.Join(dir, "material.gltf"), 1.0)
+		if err != nil {
+			t.Fatalf("%v [test %v]", err, idx)
+		}
+		metal, ok := world[0].(*Mesh).material.(Metal)
+		if !ok {
+			t.Fatalf("%T is not a Metal [test %v]", world[0].(*Mesh).material, idx)
+		}
+		if !floatEquals(metal.Fuzz, test.fuzz) || metal.Fuzz >= 1 {
+			t.Errorf("%v expected got %v instead [test %v]", test.fuzz, metal.Fuzz, idx)
+		}
+	}
+}
+
+func TestLoadGLTF_Cameras(t *testing.T) {
+	doc := gltfTestDocument(`"uri": "data:application/octet-stream;base64,` + base64.StdEncoding.EncodeToString(gltfTestBuffer()) + `", `)
+	perspective := `{"type": "perspective", "perspective": {"yfov": 0.8, "znear": 0.1}}`
+	orthographic := `{"type": "orthographic", "orthographic": {"xmag": 1, "ymag": 1, "zfar": 10, "znear": 0.1}}`
+
+	// an orthographic camera only
+	orthographicOnly := strings.Replace(doc, perspective, orthographic, 1)
+
+	// an orthographic camera (node 3, visited first) then the perspective camera (node 2)
+	orthographicFirst := strings.Replace(doc, perspective, perspective+", "+orthographic, 1)
+	orthographicFirst = strings.Replace(orthographicFirst, `"nodes": [0, 2]`, `"nodes": [0, 3, 2]`, 1)
+	orthographicFirst = strings.Replace(orthographicFirst, `"camera": 0}`, `"camera": 0},
+    {"translation": [0, 0, -5], "camera": 1}`, 1)
+
+	dir := writeTestFiles(t, map[string]string{
+		"orthographic-only.gltf":  orthographicOnly,
+		"orthographic-first.gltf": orthographicFirst,
+	})
+	defer os.RemoveAll(dir)
+
+	camera, world, err := LoadGLTF(filepath.Join(dir, "orthographic-only.gltf"), 1.0)
+	if err != nil || camera != nil || len(world) != 1 {
+		t.Errorf("no camera expected got %v %v (%v)", camera, len(world), err)
+	}
+
+	camera, _, err = LoadGLTF(filepath.Join(dir, "orthographic-first.gltf"), 1.0)
+	if err != nil || camera == nil {
+		t.Fatalf("perspective camera expected got %v (%v)", camera, err)
+	}
+	if r := camera.Ray(nil, 0.5, 0.5); !floatEquals(r.Origin.Z, 5) {
+		t.Errorf("camera at z = 5 expected got %v instead", r.Origin)
+	}
+}
+
+func TestLoadGLTF_Errors(t *testing.T) {
+	embedded := gltfTestDocument(`"uri": "data:application/octet-stream;base64,` + base64.StdEncoding.EncodeToString(gltfTestBuffer()) + `", `)
+	position := `{"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3"}`
+	view := `{"buffer": 0, "byteOffset": 0, "byteLength": 36}`
+	textured := strings.NewReplacer(
+		`"roughnessFactor": 0.2}`, `"roughnessFactor": 0.2, "baseColorTexture": {"index": 0}}`,
+		`"bufferViews": [`, `"textures": [{"source": 0}], "images": [{"bufferView": 2, "mimeType": "image/png"}], "bufferViews": [`,
+		`"byteOffset": 36, "byteLength": 6}`, `"byteOffset": 36, "byteLength": 6}, {"buffer": 0, "byteOffset": -4, "byteLength": 6}`,
+	).Replace(embedded)
+
+	dir := writeTestFiles(t, map[string]string{
+		"missing.gltf":      gltfTestDocument(`"uri": "missing.bin", `),
+		"accessor.gltf":     strings.Replace(embedded, `"POSITION": 0`, `"POSITION": 1`, 1),
+		"count.gltf":        strings.Replace(embedded, position, strings.Replace(position, `"count": 3`, `"count": -3`, 1), 1),
+		"offset.gltf":       strings.Replace(embedded, position, strings.Replace(position, `"count": 3`, `"count": 3, "byteOffset": -12`, 1), 1),
+		"stride.gltf":       strings.Replace(embedded, view, strings.Replace(view, `"byteLength": 36`, `"byteLength": 36, "byteStride": -12`, 1), 1),
+		"small-stride.gltf": strings.Replace(embedded, view, strings.Replace(view, `"byteLength": 36`, `"byteLength": 36, "byteStride": 4`, 1), 1),
+		"image.gltf":        textured,
+	})
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{"missing.gltf", "buffers[0]"},
+		{"accessor.gltf", "meshes[0].primitives[0]: POSITION: accessors[1]: type SCALAR instead of VEC3"},
+		{"count.gltf", "accessors[0]: invalid count -3"},
+		{"offset.gltf", "accessors[0]: invalid count 3 or byte offset -12"},
+		{"stride.gltf", "bufferViews[0]: invalid byte stride -12"},
+		{"small-stride.gltf", "bufferViews[0]: invalid byte stride 4"},
+		{"image.gltf", "bufferViews[2]: out of the bounds of the buffer"},
+	}
+
+	for _, test := range tests {
+		_, _, err := LoadGLTF(filepath.Join(dir, test.file), 1.0)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%v does not contain %v [test %v]", err, test.expected, test.file)
+		}
+	}
+}
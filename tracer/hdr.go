@@ -0,0 +1,490 @@
+package tracer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/***********************
+ * HDRImage
+ ************************/
+// HDRImage is an image of linear (unclamped) colors. The first row in pixels is the top of the image
+type HDRImage struct {
+	Width, Height int
+	Pixels        []Color
+}
+
+// At returns the color of the pixel at x/y (0/0 is top left)
+func (img *HDRImage) At(x, y int) Color {
+	return img.Pixels[y*img.Width+x]
+}
+
+// NewHDRImageFromImage converts an image (png, jpeg...) into an HDRImage. The image is assumed to be sRGB encoded
+// (like most images) and is converted back to linear colors (see SRGBToLinear)
+func NewHDRImageFromImage(img image.Image) *HDRImage {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	pixels := make([]Color, width*height)
+
+	k := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			c := Color{R: float64(r) / 0xFFFF, G: float64(g) / 0xFFFF, B: float64(b) / 0xFFFF}
+			pixels[k] = SRGBToLinear(c)
+			k++
+		}
+	}
+
+	return &HDRImage{width, height, pixels}
+}
+
+// LoadHDRImage loads an image from a file: Radiance (.hdr) and PFM (.pfm) are read as linear values, any other
+// extension is decoded with the image package (png, jpeg) and converted to linear values
+func LoadHDRImage(path string) (*HDRImage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var img *HDRImage
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".hdr", ".pic":
+		img, err = readRadianceHDR(bufio.NewReader(f))
+	case ".pfm":
+		img, err = readPFM(bufio.NewReader(f))
+	default:
+		var ldr image.Image
+		ldr, _, err = image.Decode(f)
+		if err == nil {
+			img = NewHDRImageFromImage(ldr)
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	return img, nil
+}
+
+// IsHDRImageFile returns true when the extension of path is one of the HDR formats supported by SaveHDRImage
+func IsHDRImageFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".exr", ".pfm", ".hdr", ".pic":
+		return true
+	}
+	return false
+}
+
+// SaveHDRImage saves the image (linear, unclamped colors) to a file whose format is chosen by the extension:
+// OpenEXR (.exr, see EXROptions), PFM (.pfm) or Radiance (.hdr, .pic)
+func SaveHDRImage(path string, img *HDRImage, options EXROptions) error {
+	var write func(w io.Writer) error
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".exr":
+		write = func(w io.Writer) error { return WriteEXR(w, img, options) }
+	case ".pfm":
+		write = func(w io.Writer) error { return writePFM(w, img) }
+	case ".hdr", ".pic":
+		write = func(w io.Writer) error { return writeRadianceHDR(w, img) }
+	default:
+		return fmt.Errorf("%v: unsupported HDR format (must be exr, pfm or hdr)", path)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		f.Close()
+		return fmt.Errorf("%v: %v", path, err)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+/***********************
+ * Radiance HDR (RGBE)
+ ************************/
+// readRadianceHDR reads an image in Radiance format (RGBE pixels, flat or run length encoded scanlines)
+func readRadianceHDR(r *bufio.Reader) (*HDRImage, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "#?") {
+		return nil, fmt.Errorf("not a radiance file (missing #? signature)")
+	}
+
+	// header (ends with an empty line)
+	for {
+		line, err = r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "FORMAT=") && line != "FORMAT=32-bit_rle_rgbe" {
+			return nil, fmt.Errorf("unsupported format [%v]", line)
+		}
+	}
+
+	// resolution string (only standard orientation -Y H +X W and its vertical flip are supported)
+	line, err = r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(line)
+	if len(fields) != 4 || (fields[0] != "-Y" && fields[0] != "+Y") || fields[2] != "+X" {
+		return nil, fmt.Errorf("unsupported resolution string [%v]", strings.TrimSpace(line))
+	}
+	height, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	width, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return nil, err
+	}
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid resolution %vx%v", width, height)
+	}
+
+	img := &HDRImage{width, height, make([]Color, width*height)}
+	scanline := make([]byte, width*4)
+
+	for y := 0; y < height; y++ {
+		if err := readRadianceScanline(r, scanline); err != nil {
+			return nil, fmt.Errorf("scanline %v: %v", y, err)
+		}
+		row := y
+		if fields[0] == "+Y" {
+			row = height - 1 - y
+		}
+		for x := 0; x < width; x++ {
+			img.Pixels[row*width+x] = rgbeToColor(scanline[x*4 : x*4+4])
+		}
+	}
+
+	return img, nil
+}
+
+// readRadianceScanline reads one scanline (width*4 bytes) handling the 3 possible encodings (flat, old run
+// length encoding and new per component run length encoding)
+func readRadianceScanline(r *bufio.Reader, scanline []byte) error {
+	width := len(scanline) / 4
+
+	var rgbe [4]byte
+	if _, err := io.ReadFull(r, rgbe[:]); err != nil {
+		return err
+	}
+
+	// new run length encoding: 2, 2, width (high byte, low byte) then each component separately
+	if width >= 8 && width < 0x8000 && rgbe[0] == 2 && rgbe[1] == 2 && rgbe[2]&0x80 == 0 {
+		if int(rgbe[2])<<8|int(rgbe[3]) != width {
+			return fmt.Errorf("scanline width mismatch")
+		}
+		for c := 0; c < 4; c++ {
+			for x := 0; x < width; {
+				count, err := r.ReadByte()
+				if err != nil {
+					return err
+				}
+				if count > 128 {
+					// run
+					n := int(count) - 128
+					if x+n > width {
+						return fmt.Errorf("run overflows scanline")
+					}
+					value, err := r.ReadByte()
+					if err != nil {
+						return err
+					}
+					for ; n > 0; n-- {
+						scanline[x*4+c] = value
+						x++
+					}
+				} else {
+					// literal values
+					n := int(count)
+					if n == 0 || x+n > width {
+						return fmt.Errorf("invalid literal count")
+					}
+					for ; n > 0; n-- {
+						value, err := r.ReadByte()
+						if err != nil {
+							return err
+						}
+						scanline[x*4+c] = value
+						x++
+					}
+				}
+			}
+		}
+		return nil
+	}
+
+	// flat or old run length encoding (1, 1, 1, count repeats the previous pixel)
+	shift := uint(0)
+	for x := 0; x < width; {
+		if rgbe[0] == 1 && rgbe[1] == 1 && rgbe[2] == 1 {
+			if x == 0 {
+				return fmt.Errorf("repeat without previous pixel")
+			}
+			n := int(rgbe[3]) << shift
+			if x+n > width {
+				return fmt.Errorf("run overflows scanline")
+			}
+			for ; n > 0; n-- {
+				copy(scanline[x*4:x*4+4], scanline[(x-1)*4:x*4])
+				x++
+			}
+			shift += 8
+		} else {
+			copy(scanline[x*4:x*4+4], rgbe[:])
+			x++
+			shift = 0
+		}
+		if x < width {
+			if _, err := io.ReadFull(r, rgbe[:]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// rgbeToColor converts a RGBE pixel (shared exponent) into a color
+func rgbeToColor(rgbe []byte) Color {
+	if rgbe[3] == 0 {
+		return Black
+	}
+	f := math.Ldexp(1.0, int(rgbe[3])-(128+8))
+	return Color{float64(rgbe[0]) * f, float64(rgbe[1]) * f, float64(rgbe[2]) * f}
+}
+
+// writeRadianceHDR writes the image in Radiance format (RGBE pixels, scanlines run length encoded when possible)
+func writeRadianceHDR(w io.Writer, img *HDRImage) error {
+	if _, err := fmt.Fprintf(w, "#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n\n-Y %v +X %v\n", img.Height, img.Width); err != nil {
+		return err
+	}
+
+	scanline := make([]byte, img.Width*4)
+	var encoded []byte
+
+	for y := 0; y < img.Height; y++ {
+		for x := 0; x < img.Width; x++ {
+			colorToRGBE(img.Pixels[y*img.Width+x], scanline[x*4:x*4+4])
+		}
+
+		// run length encoding is only defined for these widths
+		if img.Width < 8 || img.Width >= 0x8000 {
+			if _, err := w.Write(scanline); err != nil {
+				return err
+			}
+			continue
+		}
+
+		encoded = append(encoded[:0], 2, 2, byte(img.Width>>8), byte(img.Width&0xFF))
+		component := make([]byte, img.Width)
+		for c := 0; c < 4; c++ {
+			for x := range component {
+				component[x] = scanline[x*4+c]
+			}
+			encoded = appendRadianceRLE(encoded, component)
+		}
+
+		if _, err := w.Write(encoded); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// appendRadianceRLE encodes one component of a scanline: runs (of at least 4 identical values) are encoded as
+// 128+count followed by the value, anything else as count followed by the literal values
+func appendRadianceRLE(encoded []byte, data []byte) []byte {
+	const minRun, maxRun, maxLiteral = 4, 127, 128
+
+	runLength := func(x int) int {
+		n := 1
+		for x+n < len(data) && n < maxRun && data[x+n] == data[x] {
+			n++
+		}
+		return n
+	}
+
+	for x := 0; x < len(data); {
+		if n := runLength(x); n >= minRun {
+			encoded = append(encoded, byte(128+n), data[x])
+			x += n
+			continue
+		}
+
+		start := x
+		for x < len(data) && x-start < maxLiteral && runLength(x) < minRun {
+			x++
+		}
+		encoded = append(encoded, byte(x-start))
+		encoded = append(encoded, data[start:x]...)
+	}
+
+	return encoded
+}
+
+// colorToRGBE converts a color into a RGBE pixel (shared exponent, negative values are clamped to 0)
+func colorToRGBE(c Color, rgbe []byte) {
+	r, g, b := math.Max(c.R, 0), math.Max(c.G, 0), math.Max(c.B, 0)
+	max := math.Max(r, math.Max(g, b))
+	if max < 1e-32 || math.IsNaN(max) {
+		rgbe[0], rgbe[1], rgbe[2], rgbe[3] = 0, 0, 0, 0
+		return
+	}
+	mantissa, exponent := math.Frexp(max)
+	if exponent > 127 {
+		// too bright for the format => saturate
+		mantissa, exponent = 255.0/256.0, 127
+	}
+	scale := mantissa * 256.0 / max
+	rgbe[0] = byte(math.Min(r*scale, 255))
+	rgbe[1] = byte(math.Min(g*scale, 255))
+	rgbe[2] = byte(math.Min(b*scale, 255))
+	rgbe[3] = byte(exponent + 128)
+}
+
+/***********************
+ * PFM
+ ************************/
+// readPFM reads an image in Portable Float Map format (PF for color, Pf for grayscale). The sign of the scale
+// defines the endianness (negative is little endian) and rows are stored bottom to top
+func readPFM(r *bufio.Reader) (*HDRImage, error) {
+	var tokens []string
+	for len(tokens) < 4 {
+		token, err := readPFMToken(r)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	var channels int
+	switch tokens[0] {
+	case "PF":
+		channels = 3
+	case "Pf":
+		channels = 1
+	default:
+		return nil, fmt.Errorf("not a pfm file (invalid signature [%v])", tokens[0])
+	}
+
+	width, err := strconv.Atoi(tokens[1])
+	if err != nil {
+		return nil, err
+	}
+	height, err := strconv.Atoi(tokens[2])
+	if err != nil {
+		return nil, err
+	}
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid resolution %vx%v", width, height)
+	}
+	scale, err := strconv.ParseFloat(tokens[3], 64)
+	if err != nil {
+		return nil, err
+	}
+	if scale == 0 {
+		return nil, fmt.Errorf("invalid scale 0")
+	}
+
+	var order binary.ByteOrder = binary.BigEndian
+	if scale < 0 {
+		order = binary.LittleEndian
+	}
+
+	img := &HDRImage{width, height, make([]Color, width*height)}
+	row := make([]byte, width*channels*4)
+	value := func(i int) float64 {
+		return float64(math.Float32frombits(order.Uint32(row[i*4:])))
+	}
+
+	for y := height - 1; y >= 0; y-- {
+		if _, err := io.ReadFull(r, row); err != nil {
+			return nil, fmt.Errorf("row %v: %v", height-1-y, err)
+		}
+		for x := 0; x < width; x++ {
+			var c Color
+			if channels == 3 {
+				c = Color{value(x * 3), value(x*3 + 1), value(x*3 + 2)}
+			} else {
+				v := value(x)
+				c = Color{v, v, v}
+			}
+			img.Pixels[y*width+x] = c
+		}
+	}
+
+	return img, nil
+}
+
+// writePFM writes the image in Portable Float Map format (PF, little endian, rows bottom to top)
+func writePFM(w io.Writer, img *HDRImage) error {
+	if _, err := fmt.Fprintf(w, "PF\n%v %v\n-1.0\n", img.Width, img.Height); err != nil {
+		return err
+	}
+
+	row := make([]byte, img.Width*3*4)
+	for y := img.Height - 1; y >= 0; y-- {
+		for x := 0; x < img.Width; x++ {
+			c := img.Pixels[y*img.Width+x]
+			binary.LittleEndian.PutUint32(row[x*12:], math.Float32bits(float32(c.R)))
+			binary.LittleEndian.PutUint32(row[x*12+4:], math.Float32bits(float32(c.G)))
+			binary.LittleEndian.PutUint32(row[x*12+8:], math.Float32bits(float32(c.B)))
+		}
+		if _, err := w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readPFMToken reads a header token (the last token, the scale, is followed by exactly one whitespace character
+// which is consumed)
+func readPFMToken(r *bufio.Reader) (string, error) {
+	var token []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if b == ' ' || b == '\n' || b == '\r' || b == '\t' {
+			if len(token) > 0 {
+				return string(token), nil
+			}
+			continue
+		}
+		token = append(token, b)
+	}
+}
@@ -0,0 +1,124 @@
+package tracer
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testHDRImage creates an image with a mix of smooth areas (runs), noise and values way above 1
+func testHDRImage(width, height int) *HDRImage {
+	rnd := rand.New(rand.NewSource(2017))
+	img := &HDRImage{width, height, make([]Color, width*height)}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var c Color
+			switch {
+			case x < width/3:
+				c = Color{0.25, 0.5, 1}
+			case x < 2*width/3:
+				c = Color{rnd.Float64(), rnd.Float64() * 10, rnd.Float64() * 1000}
+			default:
+				c = Color{float64(x) / float64(width), float64(y) / float64(height), 0}
+			}
+			img.Pixels[y*width+x] = c
+		}
+	}
+	return img
+}
+
+// relativeError computes the largest relative error between 2 colors
+func relativeError(expected, actual Color) float64 {
+	e := 0.0
+	for _, v := range [][2]float64{{expected.R, actual.R}, {expected.G, actual.G}, {expected.B, actual.B}} {
+		d := math.Abs(v[0] - v[1])
+		if m := math.Max(math.Abs(v[0]), 1e-3); d/m > e {
+			e = d / m
+		}
+	}
+	return e
+}
+
+func TestWritePFM_RoundTrip(t *testing.T) {
+	img := testHDRImage(13, 7)
+
+	var buf bytes.Buffer
+	if err := writePFM(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := readPFM(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for k, c := range img.Pixels {
+		if e := relativeError(c, read.Pixels[k]); e > 1e-6 {
+			t.Errorf("%v expected got %v instead [pixel %v]", c, read.Pixels[k], k)
+		}
+	}
+}
+
+func TestWriteRadianceHDR_RoundTrip(t *testing.T) {
+	// 5 is too narrow for run length encoding (flat scanlines)
+	for _, width := range []int{5, 300} {
+		img := testHDRImage(width, 9)
+
+		var buf bytes.Buffer
+		if err := writeRadianceHDR(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+
+		read, err := readRadianceHDR(bufio.NewReader(&buf))
+		if err != nil {
+			t.Fatalf("%v [width %v]", err, width)
+		}
+
+		for k, c := range img.Pixels {
+			// rgbe has 8 bits of mantissa shared by the 3 components => precision relative to the brightest one
+			max := math.Max(c.R, math.Max(c.G, c.B))
+			r := read.Pixels[k]
+			if math.Abs(c.R-r.R) > max/128 || math.Abs(c.G-r.G) > max/128 || math.Abs(c.B-r.B) > max/128 {
+				t.Errorf("%v expected got %v instead [pixel %v, width %v]", c, r, k, width)
+			}
+		}
+	}
+}
+
+func TestSaveHDRImage(t *testing.T) {
+	dir := t.TempDir()
+	img := testHDRImage(20, 10)
+
+	for _, name := range []string{"image.exr", "image.pfm", "image.hdr"} {
+		path := filepath.Join(dir, name)
+		if !IsHDRImageFile(path) {
+			t.Errorf("%v should be an hdr image file", name)
+		}
+		if err := SaveHDRImage(path, img, EXROptions{}); err != nil {
+			t.Errorf("unexpected error %v [%v]", err, name)
+		}
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("%v not saved", name)
+		}
+	}
+
+	// saving again a smaller image truncates the file
+	path := filepath.Join(dir, "image.pfm")
+	if err := SaveHDRImage(path, testHDRImage(2, 2), EXROptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if read, err := LoadHDRImage(path); err != nil || read.Width != 2 || read.Height != 2 {
+		t.Errorf("2x2 image expected got %v (%v)", read, err)
+	}
+
+	if IsHDRImageFile("image.png") {
+		t.Errorf("png is not an hdr image file")
+	}
+	if err := SaveHDRImage(filepath.Join(dir, "image.png"), img, EXROptions{}); err == nil {
+		t.Errorf("error expected for png")
+	}
+}
@@ -0,0 +1,36 @@
+package tracer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+/***********************
+ * Models
+ ************************/
+// LoadModel loads a model based on the extension of the file (obj, ply, stl, gltf or glb)
+//	for glTF files only the geometry is kept (the camera is ignored)
+func LoadModel(path string) (HitableList, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".obj":
+		return LoadOBJ(path)
+	case ".ply":
+		mesh, err := LoadPLY(path)
+		if err != nil {
+			return nil, err
+		}
+		return HitableList{mesh}, nil
+	case ".stl":
+		mesh, err := LoadSTL(path)
+		if err != nil {
+			return nil, err
+		}
+		return HitableList{mesh}, nil
+	case ".gltf", ".glb":
+		_, model, err := LoadGLTF(path, 1.0)
+		return model, err
+	}
+
+	return nil, fmt.Errorf("%v: unsupported model format (must be obj, ply, stl, gltf or glb)", path)
+}
@@ -0,0 +1,34 @@
+package tracer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadModel(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"model.obj": "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 3\n",
+		"model.STL": "solid model\nfacet normal 0 0 1\nouter loop\nvertex 0 0 0\nvertex 1 0 0\nvertex 0 1 0\nendloop\nendfacet\nendsolid model\n",
+		"model.ply": "ply\nformat ascii 1.0\nelement vertex 3\nproperty float x\nproperty float y\nproperty float z\nelement face 1\nproperty list uchar int vertex_indices\nend_header\n0 0 0\n1 0 0\n0 1 0\n3 0 1 2\n",
+		"model.3ds": "",
+	})
+	defer os.RemoveAll(dir)
+
+	// the format is chosen by the extension (case insensitive)
+	for _, name := range []string{"model.obj", "model.STL", "model.ply"} {
+		model, err := LoadModel(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("%v [test %v]", err, name)
+			continue
+		}
+		if len(model) != 1 || model[0].(*Mesh).TriangleCount() != 1 {
+			t.Errorf("expected 1 mesh with 1 triangle got %v [test %v]", model, name)
+		}
+	}
+
+	if _, err := LoadModel(filepath.Join(dir, "model.3ds")); err == nil || !strings.Contains(err.Error(), "unsupported model format") {
+		t.Errorf("unsupported format error expected got %v instead", err)
+	}
+}
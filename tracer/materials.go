@@ -0,0 +1,180 @@
+package tracer
+
+import (
+	"math"
+)
+
+/***********************
+ * Material
+ ************************/
+// Material defines how a material scatter light and how much light it emits (Black for most materials)
+type Material interface {
+	Scatter(r *Ray, rec *HitRecord) (wasScattered bool, attenuation *Color, scattered *Ray)
+	Emitted(rec *HitRecord) Color
+}
+
+// diffuseMaterial is implemented by materials which reflect light equally in all directions, allowing the light
+// coming from a sampled background to be computed explicitly (see color)
+type diffuseMaterial interface {
+	diffuseAlbedo(rec *HitRecord) Color
+}
+
+/***********************
+ * Lambertian material (diffuse only)
+ ************************/
+// Lambertian scatters light in random directions (cosine distributed) attenuated by the albedo
+type Lambertian struct {
+	Albedo Texture
+}
+
+func (mat Lambertian) diffuseAlbedo(rec *HitRecord) Color {
+	return mat.Albedo.Value(rec.U, rec.V, rec.P)
+}
+
+func (mat Lambertian) Scatter(r *Ray, rec *HitRecord) (bool, *Color, *Ray) {
+	return scatterDiffuse(r, rec, mat.Albedo.Value(rec.U, rec.V, rec.P))
+}
+
+func (mat Lambertian) Emitted(rec *HitRecord) Color {
+	return Black
+}
+
+// scatterDiffuse scatters the ray in a random direction (cosine distributed) attenuated by albedo
+func scatterDiffuse(r *Ray, rec *HitRecord, albedo Color) (bool, *Color, *Ray) {
+	target := rec.P.Translate(rec.Normal).Translate(RandomInUnitSphere(r.Rnd))
+	scattered := &Ray{rec.P, target.Sub(rec.P), r.Rnd}
+	return true, &albedo, scattered
+}
+
+/***********************
+ * Vertex color material
+ ************************/
+// vertexColorMaterial is the Lambertian material of the meshes with per vertex colors (see Mesh): the albedo is the
+// color interpolated at the hit point which is stored in the hit record so that no material is created per hit
+type vertexColorMaterial struct{}
+
+func (mat vertexColorMaterial) diffuseAlbedo(rec *HitRecord) Color {
+	return rec.color
+}
+
+func (mat vertexColorMaterial) Scatter(r *Ray, rec *HitRecord) (bool, *Color, *Ray) {
+	return scatterDiffuse(r, rec, rec.color)
+}
+
+func (mat vertexColorMaterial) Emitted(rec *HitRecord) Color {
+	return Black
+}
+
+/***********************
+ * Metal material
+ ************************/
+// Metal reflects light (fuzz in [0,1] perturbs the reflected ray: 0 is a perfect mirror)
+type Metal struct {
+	Albedo Texture
+	Fuzz   float64
+}
+
+// roughMetalFuzz is the fuzz of a fully rough Metal converted from other material models (Metal only perturbs the
+// reflected ray when Fuzz < 1)
+const roughMetalFuzz = 0.999
+
+func (mat Metal) Scatter(r *Ray, rec *HitRecord) (bool, *Color, *Ray) {
+	reflected := r.Direction.Unit().Reflect(rec.Normal)
+	if mat.Fuzz < 1 {
+		reflected = reflected.Add(RandomInUnitSphere(r.Rnd).Scale(mat.Fuzz))
+	}
+	scattered := &Ray{rec.P, reflected, r.Rnd}
+
+	if Dot(scattered.Direction, rec.Normal) > 0 {
+		attenuation := mat.Albedo.Value(rec.U, rec.V, rec.P)
+		return true, &attenuation, scattered
+	}
+
+	return false, nil, nil
+}
+
+func (mat Metal) Emitted(rec *HitRecord) Color {
+	return Black
+}
+
+/***********************
+ * Dielectric material (glass)
+ ************************/
+// Dielectric reflects or refracts light depending on the angle (RefIdx is the refraction index, ex: 1.5 for glass)
+type Dielectric struct {
+	RefIdx float64
+}
+
+// Refract returns a refracted vector (or not if there is no refraction possible)
+func (v Vec3) Refract(n Vec3, niOverNt float64) (bool, *Vec3) {
+	uv := v.Unit()
+	un := n.Unit()
+
+	dt := Dot(uv, un)
+	discriminant := 1.0 - niOverNt*niOverNt*(1-dt*dt)
+	if discriminant > 0 {
+		refracted := uv.Sub(un.Scale(dt)).Scale(niOverNt).Sub(un.Scale(math.Sqrt(discriminant)))
+		return true, &refracted
+	}
+
+	return false, nil
+}
+
+func schlick(cosine float64, iRefIdx float64) float64 {
+	r0 := (1.0 - iRefIdx) / (1.0 + iRefIdx)
+	r0 = r0 * r0
+	return r0 + (1.0-r0)*math.Pow(1.0-cosine, 5)
+}
+
+func (die Dielectric) Scatter(r *Ray, rec *HitRecord) (bool, *Color, *Ray) {
+	var (
+		outwardNormal Vec3
+		niOverNt      float64
+		cosine        float64
+	)
+
+	dotRayNormal := Dot(r.Direction, rec.Normal);
+	if dotRayNormal > 0 {
+		outwardNormal = rec.Normal.Negate()
+		niOverNt = die.RefIdx;
+		cosine = dotRayNormal / r.Direction.Length()
+		cosine = math.Sqrt(1.0 - die.RefIdx*die.RefIdx*(1.0-cosine*cosine));
+	} else {
+		outwardNormal = rec.Normal;
+		niOverNt = 1.0 / die.RefIdx
+		cosine = -dotRayNormal / r.Direction.Length()
+	}
+
+	wasRefracted, refracted := r.Direction.Refract(outwardNormal, niOverNt)
+
+	var direction Vec3
+
+	// refract only with some probability
+	if wasRefracted && r.Rnd.Float64() >= schlick(cosine, die.RefIdx) {
+		direction = *refracted
+	} else {
+		direction = r.Direction.Unit().Reflect(rec.Normal)
+	}
+
+	return true, &White, &Ray{rec.P, direction, r.Rnd}
+}
+
+func (die Dielectric) Emitted(rec *HitRecord) Color {
+	return Black
+}
+
+/***********************
+ * DiffuseLight material (emissive)
+ ************************/
+// DiffuseLight is a material which emits light (and does not scatter any)
+type DiffuseLight struct {
+	Emit Texture
+}
+
+func (mat DiffuseLight) Scatter(r *Ray, rec *HitRecord) (bool, *Color, *Ray) {
+	return false, nil, nil
+}
+
+func (mat DiffuseLight) Emitted(rec *HitRecord) Color {
+	return mat.Emit.Value(rec.U, rec.V, rec.P)
+}
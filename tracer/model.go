@@ -1,9 +1,10 @@
-package main
+package tracer
 
 import (
 	"math"
 )
 
+// Rnd is the source of random numbers (in [0,1)) used while rendering (see Ray.Rnd)
 type Rnd interface {
 	Float64() float64
 }
@@ -93,10 +94,11 @@ func (p Point3) Vec3() Vec3 {
  * Ray
  ************************/
 // Ray represents a ray defined by its origin and direction
+//	Rnd is the source of random numbers of the goroutine casting the ray (to be used by materials when scattering)
 type Ray struct {
 	Origin    Point3
 	Direction Vec3
-	rnd Rnd
+	Rnd       Rnd
 }
 
 // PointAt returns a new point along the ray (0 will return the origin)
@@ -132,6 +134,11 @@ func (c Color) Add(c2 Color) Color {
 	return Color{R: c.R + c2.R, G: c.G + c2.G, B: c.B + c2.B}
 }
 
+// Luminance returns the (relative) luminance of the color (Rec. 709 weights)
+func (c Color) Luminance() float64 {
+	return 0.2126*c.R + 0.7152*c.G + 0.0722*c.B
+}
+
 // PixelValue converts a raw Color into a pixel value (0-255) packed into a uint32
 func (c Color) PixelValue() uint32 {
 	r := uint32(math.Min(255.0, c.R*255.99))
@@ -144,43 +151,82 @@ func (c Color) PixelValue() uint32 {
 /***********************
  * Hitable
  ************************/
+// HitRecord describes where a ray hit an object (returned by Hitable.Hit)
 type HitRecord struct {
-	t        float64  // which t generated the hit
-	p        Point3   // which point when hit
-	normal   Vec3     // normal at that point
-	material Material // the material associated to this record
+	T        float64  // which t generated the hit
+	P        Point3   // which point when hit
+	Normal   Vec3     // normal at that point
+	Material Material // the material associated to this record
+	U, V     float64  // surface coordinates at that point (for textures)
+	color    Color    // interpolated vertex color (only for meshes with per vertex colors, see Mesh)
 }
 
 // Hitable defines the interface of objects that can be hit by a ray
+//	BoundingBox returns false if the object cannot be bounded (ex: infinite plane)
 type Hitable interface {
-	hit(r *Ray, tMin float64, tMax float64) (bool, *HitRecord)
+	Hit(r *Ray, tMin float64, tMax float64) (bool, *HitRecord)
+	BoundingBox() (bool, *AABB)
 }
 
 // HitableList defines a simple list of hitable
 type HitableList []Hitable
 
-// hit defines the method for a list of hitables: will return the one closest
-func (hl HitableList) hit(r *Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+// Hit defines the method for a list of hitables: will return the one closest
+func (hl HitableList) Hit(r *Ray, tMin float64, tMax float64) (bool, *HitRecord) {
 	var res *HitRecord
 	hitAnything := false
 
 	closestSoFar := tMax
 
 	for _, h := range hl {
-		if hit, hr := h.hit(r, tMin, closestSoFar); hit {
+		if hit, hr := h.Hit(r, tMin, closestSoFar); hit {
 			hitAnything = true
 			res = hr
-			closestSoFar = hr.t
+			closestSoFar = hr.T
 		}
 	}
 
 	return hitAnything, res
 }
 
+// BoundingBox returns the box surrounding every hitable in the list (false if any of them cannot be bounded)
+func (hl HitableList) BoundingBox() (bool, *AABB) {
+	if len(hl) == 0 {
+		return false, nil
+	}
+
+	var res *AABB
+
+	for _, h := range hl {
+		ok, box := h.BoundingBox()
+		if !ok {
+			return false, nil
+		}
+		if res == nil {
+			res = box
+		} else {
+			res = SurroundingBox(res, box)
+		}
+	}
+
+	return true, res
+}
+
 /***********************
  * Utilities functions
  ************************/
-func randomInUnitSphere(rnd Rnd) Vec3 {
+// RandomInUnitSphere returns a random point inside the sphere of radius 1. When rnd is a Sampler, the point is
+// computed from a 2D sample (direction) and a 1D sample (distance) so that the stratification is preserved,
+// otherwise points in the cube are rejected until one is inside the sphere
+func RandomInUnitSphere(rnd Rnd) Vec3 {
+	if _, ok := rnd.(Sampler); ok {
+		u, v := sample2D(rnd)
+		z := 1.0 - 2.0*u
+		r := math.Sqrt(math.Max(0, 1.0-z*z))
+		phi := 2.0 * math.Pi * v
+		return Vec3{r * math.Cos(phi), r * math.Sin(phi), z}.Scale(math.Cbrt(rnd.Float64()))
+	}
+
 	for {
 		p := Vec3{2.0*rnd.Float64() - 1.0, 2.0*rnd.Float64() - 1.0, 2.0*rnd.Float64() - 1.0}
 		if Dot(p, p) < 1.0 {
@@ -189,7 +235,15 @@ func randomInUnitSphere(rnd Rnd) Vec3 {
 	}
 }
 
-func randomInUnitDisk(rnd Rnd) Vec3 {
+// RandomInUnitDisk returns a random point inside the disk of radius 1 (z = 0). When rnd is a Sampler, the point is
+// computed from a 2D sample with the concentric mapping (Shirley and Chiu) which preserves the stratification,
+// otherwise points in the square are rejected until one is inside the disk
+func RandomInUnitDisk(rnd Rnd) Vec3 {
+	if _, ok := rnd.(Sampler); ok {
+		u, v := sample2D(rnd)
+		return concentricDisk(u, v)
+	}
+
 	for {
 		p := Vec3{2.0*rnd.Float64() - 1.0, 2.0*rnd.Float64() - 1.0, 0}
 		if Dot(p, p) < 1.0 {
@@ -197,3 +251,19 @@ func randomInUnitDisk(rnd Rnd) Vec3 {
 		}
 	}
 }
+
+// concentricDisk maps the unit square onto the unit disk: the concentric squares become concentric circles
+func concentricDisk(u, v float64) Vec3 {
+	a, b := 2.0*u-1.0, 2.0*v-1.0
+	if a == 0 && b == 0 {
+		return Vec3{}
+	}
+
+	var r, phi float64
+	if math.Abs(a) > math.Abs(b) {
+		r, phi = a, (math.Pi/4.0)*(b/a)
+	} else {
+		r, phi = b, math.Pi/2.0-(math.Pi/4.0)*(a/b)
+	}
+	return Vec3{r * math.Cos(phi), r * math.Sin(phi), 0}
+}
@@ -1,4 +1,4 @@
-package main
+package tracer
 
 import "testing"
 
@@ -46,7 +46,7 @@ func floatEquals(a, b float64) bool {
 func TestRandomInUnitSphere(t *testing.T) {
 	rndMock := RndMock{floats: []float64{0.8, 0.7, 0.6}}
 
-	r := randomInUnitSphere(&rndMock)
+	r := RandomInUnitSphere(&rndMock)
 
 	if !floatEquals(r.X, 2.0 * 0.8 - 1.0) || !floatEquals(r.Y,  2.0 * 0.7 - 1.0) || !floatEquals(r.Z, 2.0 * 0.6 - 1.0) {
 		t.Errorf("unexpected vector %v/%v/%v", r.X, r.Y, r.Z)
@@ -54,7 +54,7 @@ func TestRandomInUnitSphere(t *testing.T) {
 
 	rndMock = RndMock{floats: []float64{0.99, 0.99, 0.99, 0.8, 0.7, 0.6}}
 
-	r = randomInUnitSphere(&rndMock)
+	r = RandomInUnitSphere(&rndMock)
 
 	if !floatEquals(r.X, 2.0 * 0.8 - 1.0) || !floatEquals(r.Y,  2.0 * 0.7 - 1.0) || !floatEquals(r.Z, 2.0 * 0.6 - 1.0) {
 		t.Errorf("unexpected vector %v/%v/%v", r.X, r.Y, r.Z)
@@ -0,0 +1,536 @@
+package tracer
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+/***********************
+ * ParseError
+ ************************/
+// ParseError reports an error while parsing a file (the file and line where it occurred)
+type ParseError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%v:%v: %v", e.File, e.Line, e.Err)
+}
+
+/***********************
+ * Wavefront OBJ
+ ************************/
+// defaultModelMaterial is the material used for models (or faces) which do not define any
+var defaultModelMaterial = Lambertian{Color{R: 0.8, G: 0.8, B: 0.8}}
+
+// LoadOBJ loads a Wavefront OBJ file (and the MTL files it references) and returns one mesh per material (all
+// sharing the same vertex buffers).
+//	faces with more than 3 vertices are triangulated (fan)
+//	negative indices are relative to the end of the vertices read so far
+//	faces without normals (vn) are smooth shaded within a smoothing group (s 1...) and flat shaded otherwise (s off)
+// Materials are mapped from the MTL definitions (see mtlDefinition.material)
+func LoadOBJ(path string) (HitableList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	parser := &objParser{
+		path:      path,
+		materials: make(map[string]Material),
+		groups:    make(map[string]*objGroup),
+		vertices:  make(map[objVertexKey]int),
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parser.line++
+		if err := parser.parseLine(scanner.Text()); err != nil {
+			return nil, &ParseError{path, parser.line, err}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &ParseError{path, parser.line, err}
+	}
+
+	return parser.meshes(), nil
+}
+
+// objVertexKey identifies a unique vertex in the mesh
+//	normal is the index of the normal (vn) or -1 when computed (for the smoothing group or the face)
+//	smoothing is the smoothing group of a computed normal (0 when flat shaded)
+//	face is the index of the face of a flat shaded vertex (not shared) and -1 otherwise
+type objVertexKey struct {
+	position, uv, normal int
+	smoothing, face      int
+}
+
+// objGroup is the set of triangles using the same material
+type objGroup struct {
+	material Material
+	indices  []int
+}
+
+type objParser struct {
+	path string
+	line int
+
+	// data read from the file
+	positions []Point3
+	uvs       []UV
+	normals   []Vec3
+
+	// material handling
+	materials map[string]Material
+	groups    map[string]*objGroup
+	order     []string
+	current   *objGroup
+
+	// smoothing group (0 is off)
+	smoothing int
+	faces     int
+
+	// output vertex buffers
+	vertices       map[objVertexKey]int
+	outPositions   []Point3
+	outUVs         []UV
+	outNormals     []Vec3
+	hasUVs         bool
+	hasNormals     bool
+	computedNormal []bool
+}
+
+func (p *objParser) parseLine(line string) error {
+	if i := strings.Index(line, "#"); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	switch fields[0] {
+	case "v":
+		v, err := parseFloats(fields[1:], 3)
+		if err != nil {
+			return err
+		}
+		p.positions = append(p.positions, Point3{v[0], v[1], v[2]})
+	case "vt":
+		v, err := parseFloats(fields[1:], 1)
+		if err != nil {
+			return err
+		}
+		uv := UV{U: v[0]}
+		if len(v) > 1 {
+			uv.V = v[1]
+		}
+		p.uvs = append(p.uvs, uv)
+	case "vn":
+		v, err := parseFloats(fields[1:], 3)
+		if err != nil {
+			return err
+		}
+		p.normals = append(p.normals, Vec3{v[0], v[1], v[2]}.Unit())
+	case "f":
+		return p.parseFace(fields[1:])
+	case "s":
+		if len(fields) < 2 {
+			return fmt.Errorf("missing smoothing group")
+		}
+		if fields[1] == "off" {
+			p.smoothing = 0
+		} else {
+			s, err := strconv.Atoi(fields[1])
+			if err != nil || s < 0 {
+				return fmt.Errorf("invalid smoothing group [%v]", fields[1])
+			}
+			p.smoothing = s
+		}
+	case "mtllib":
+		if len(fields) < 2 {
+			return fmt.Errorf("missing material library")
+		}
+		// file names can contain spaces
+		mtlPath := filepath.Join(filepath.Dir(p.path), strings.Join(fields[1:], " "))
+		materials, err := loadMTL(mtlPath)
+		if err != nil {
+			return err
+		}
+		for name, material := range materials {
+			p.materials[name] = material
+		}
+	case "usemtl":
+		if len(fields) < 2 {
+			return fmt.Errorf("missing material name")
+		}
+		name := fields[1]
+		material, ok := p.materials[name]
+		if !ok {
+			return fmt.Errorf("unknown material [%v]", name)
+		}
+		p.useGroup(name, material)
+	}
+
+	// other statements (o, g, l, p...) are ignored
+
+	return nil
+}
+
+// useGroup makes the group for the material the current one (creating it if necessary)
+func (p *objParser) useGroup(name string, material Material) {
+	group, ok := p.groups[name]
+	if !ok {
+		group = &objGroup{material: material}
+		p.groups[name] = group
+		p.order = append(p.order, name)
+	}
+	p.current = group
+}
+
+// resolveIndex converts an OBJ index (1 based or negative relative to the end) into a 0 based index
+func resolveIndex(s string, count int, kind string) (int, error) {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %v index [%v]", kind, s)
+	}
+	switch {
+	case i > 0 && i <= count:
+		return i - 1, nil
+	case i < 0 && -i <= count:
+		return count + i, nil
+	}
+	return 0, fmt.Errorf("%v index %v out of range (%v defined)", kind, i, count)
+}
+
+func (p *objParser) parseFace(fields []string) error {
+	if len(fields) < 3 {
+		return fmt.Errorf("face with less than 3 vertices")
+	}
+
+	p.faces++
+
+	if p.current == nil {
+		p.useGroup("", defaultModelMaterial)
+	}
+
+	keys := make([]objVertexKey, len(fields))
+	for i, field := range fields {
+		parts := strings.Split(field, "/")
+		if len(parts) > 3 {
+			return fmt.Errorf("invalid face vertex [%v]", field)
+		}
+
+		position, err := resolveIndex(parts[0], len(p.positions), "vertex")
+		if err != nil {
+			return err
+		}
+		key := objVertexKey{position: position, uv: -1, normal: -1, face: -1}
+
+		if len(parts) > 1 && parts[1] != "" {
+			if key.uv, err = resolveIndex(parts[1], len(p.uvs), "texture"); err != nil {
+				return err
+			}
+		}
+
+		if len(parts) > 2 && parts[2] != "" {
+			if key.normal, err = resolveIndex(parts[2], len(p.normals), "normal"); err != nil {
+				return err
+			}
+		} else if p.smoothing != 0 {
+			key.smoothing = p.smoothing
+		} else {
+			key.face = p.faces
+		}
+
+		keys[i] = key
+	}
+
+	// face normal (Newell's method, works for non planar polygons) used for smoothing and flat shading
+	var faceNormal Vec3
+	for i := range keys {
+		c := p.positions[keys[i].position]
+		n := p.positions[keys[(i+1)%len(keys)].position]
+		faceNormal = faceNormal.Add(Vec3{(c.Y - n.Y) * (c.Z + n.Z), (c.Z - n.Z) * (c.X + n.X), (c.X - n.X) * (c.Y + n.Y)})
+	}
+
+	indices := make([]int, len(keys))
+	for i, key := range keys {
+		indices[i] = p.vertex(key, faceNormal)
+	}
+
+	// triangulate (fan)
+	for i := 1; i < len(indices)-1; i++ {
+		p.current.indices = append(p.current.indices, indices[0], indices[i], indices[i+1])
+	}
+
+	return nil
+}
+
+// vertex returns the index of the output vertex for the key (creating it if necessary)
+func (p *objParser) vertex(key objVertexKey, faceNormal Vec3) int {
+	index, ok := p.vertices[key]
+	if !ok {
+		index = len(p.outPositions)
+		p.vertices[key] = index
+		p.outPositions = append(p.outPositions, p.positions[key.position])
+
+		uv := UV{}
+		if key.uv >= 0 {
+			uv = p.uvs[key.uv]
+			p.hasUVs = true
+		}
+		p.outUVs = append(p.outUVs, uv)
+
+		normal := Vec3{}
+		computed := key.normal < 0
+		switch {
+		case !computed:
+			normal = p.normals[key.normal]
+			p.hasNormals = true
+		case key.smoothing != 0:
+			// smoothing group
+			p.hasNormals = true
+		}
+		p.outNormals = append(p.outNormals, normal)
+		p.computedNormal = append(p.computedNormal, computed)
+	}
+
+	// accumulate the (area weighted) face normals for computed normals
+	if p.computedNormal[index] {
+		p.outNormals[index] = p.outNormals[index].Add(faceNormal)
+	}
+
+	return index
+}
+
+// meshes creates one mesh per material sharing the vertex buffers
+func (p *objParser) meshes() HitableList {
+	var normals []Vec3
+	if p.hasNormals {
+		normals = p.outNormals
+		for i, n := range normals {
+			if p.computedNormal[i] && n.Length() > 0 {
+				normals[i] = n.Unit()
+			}
+		}
+	}
+
+	var uvs []UV
+	if p.hasUVs {
+		uvs = p.outUVs
+	}
+
+	var meshes HitableList
+	for _, name := range p.order {
+		group := p.groups[name]
+		if len(group.indices) > 0 {
+			meshes = append(meshes, NewMesh(p.outPositions, normals, uvs, group.indices, group.material))
+		}
+	}
+
+	return meshes
+}
+
+/***********************
+ * MTL
+ ************************/
+// mtlDefinition holds the values read from a MTL file for one material
+type mtlDefinition struct {
+	kd, ks, ke  Color
+	ns, ni, d   float64
+	illum       int
+	mapKd       string
+	hasKs, hasD bool
+}
+
+// loadMTL loads all the materials defined in a MTL file
+func loadMTL(path string) (map[string]Material, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	materials := make(map[string]Material)
+
+	var name string
+	var current *mtlDefinition
+	line := 0
+
+	// converts the current definition into a material
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		material, err := current.material(filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		materials[name] = material
+		return nil
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line++
+
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "newmtl" {
+			if err := flush(); err != nil {
+				return nil, &ParseError{path, line, err}
+			}
+			if len(fields) < 2 {
+				return nil, &ParseError{path, line, fmt.Errorf("missing material name")}
+			}
+			name = fields[1]
+			current = &mtlDefinition{kd: Color{R: 0.8, G: 0.8, B: 0.8}, ni: 1.5, d: 1.0}
+			continue
+		}
+
+		if current == nil {
+			return nil, &ParseError{path, line, fmt.Errorf("[%v] before newmtl", fields[0])}
+		}
+
+		if err := current.parse(fields); err != nil {
+			return nil, &ParseError{path, line, err}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &ParseError{path, line, err}
+	}
+
+	if err := flush(); err != nil {
+		return nil, &ParseError{path, line, err}
+	}
+
+	return materials, nil
+}
+
+func (def *mtlDefinition) parse(fields []string) error {
+	var err error
+
+	parseColorFields := func() (Color, error) {
+		v, err := parseFloats(fields[1:], 1)
+		if err != nil {
+			return Black, err
+		}
+		// a single value is a gray
+		if len(v) < 3 {
+			return Color{v[0], v[0], v[0]}, nil
+		}
+		return Color{v[0], v[1], v[2]}, nil
+	}
+
+	parseFloatField := func() (float64, error) {
+		v, err := parseFloats(fields[1:], 1)
+		if err != nil {
+			return 0, err
+		}
+		return v[0], nil
+	}
+
+	switch fields[0] {
+	case "Kd":
+		def.kd, err = parseColorFields()
+	case "Ks":
+		def.ks, err = parseColorFields()
+		def.hasKs = true
+	case "Ke":
+		def.ke, err = parseColorFields()
+	case "Ns":
+		def.ns, err = parseFloatField()
+	case "Ni":
+		def.ni, err = parseFloatField()
+	case "d":
+		def.d, err = parseFloatField()
+		def.hasD = true
+	case "Tr":
+		// Tr is the inverse of d (only used if d is not defined)
+		var tr float64
+		tr, err = parseFloatField()
+		if !def.hasD {
+			def.d = 1 - tr
+		}
+	case "illum":
+		if len(fields) < 2 {
+			return fmt.Errorf("missing illumination model")
+		}
+		def.illum, err = strconv.Atoi(fields[1])
+	case "map_Kd":
+		if len(fields) < 2 {
+			return fmt.Errorf("missing texture file")
+		}
+		// the file name is the last field (options like -s or -o are not supported)
+		def.mapKd = fields[len(fields)-1]
+	}
+
+	// other statements (Ka, map_Bump...) are ignored
+
+	return err
+}
+
+// material converts the MTL definition into a material of the ray tracer
+//	Ke (emission) => DiffuseLight
+//	d < 1 (transparent) or illum 4/6/7/9 (refraction) => Dielectric using Ni as the refraction index
+//	Ks more important than Kd or illum 3/5 (reflection) => Metal using Ks as albedo and Ns for the fuzz
+//	otherwise => Lambertian using Kd (or map_Kd texture) as albedo
+func (def *mtlDefinition) material(dir string) (Material, error) {
+	switch {
+	case def.ke.Luminance() > 0:
+		return DiffuseLight{def.ke}, nil
+
+	case def.d < 1 || def.illum == 4 || def.illum == 6 || def.illum == 7 || def.illum == 9:
+		return Dielectric{def.ni}, nil
+
+	case def.hasKs && (def.ks.Luminance() > def.kd.Luminance() || def.illum == 3 || def.illum == 5):
+		// the specular exponent is converted into a roughness (sharp highlight => low fuzz, no Ns => fully rough)
+		fuzz := math.Min(math.Sqrt(2/(def.ns+2)), roughMetalFuzz)
+		return Metal{def.ks, fuzz}, nil
+	}
+
+	if def.mapKd != "" {
+		texture, err := LoadImageTexture(filepath.Join(dir, def.mapKd))
+		if err != nil {
+			return nil, err
+		}
+		return Lambertian{texture}, nil
+	}
+
+	return Lambertian{def.kd}, nil
+}
+
+/***********************
+ * Utilities functions
+ ************************/
+// parseFloats parses all the fields as float (at least min of them)
+func parseFloats(fields []string, min int) ([]float64, error) {
+	if len(fields) < min {
+		return nil, fmt.Errorf("expected at least %v values got %v", min, len(fields))
+	}
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number [%v]", field)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
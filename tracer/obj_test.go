@@ -0,0 +1,166 @@
+package tracer
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "ray-tracing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadOBJ(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"model.mtl": `
+newmtl red
+Kd 0.8 0.1 0.1
+
+newmtl chrome
+Kd 0 0 0
+Ks 0.9 0.9 0.9
+Ns 1000
+
+newmtl glass
+Ni 1.3
+d 0.1
+`,
+		"model.obj": `# a quad (negative indices) and a triangle
+mtllib model.mtl
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+usemtl red
+f -4 -3 -2 -1
+usemtl chrome
+s 1
+f 1 2 3
+usemtl glass
+f 1 3 4
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	meshes, err := LoadOBJ(filepath.Join(dir, "model.obj"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(meshes) != 3 {
+		t.Fatalf("expected 3 meshes (one per material) got %v", len(meshes))
+	}
+
+	red := meshes[0].(*Mesh)
+	if red.TriangleCount() != 2 || red.material != (Lambertian{Color{0.8, 0.1, 0.1}}) {
+		t.Errorf("unexpected red mesh %v triangles %v", red.TriangleCount(), red.material)
+	}
+
+	chrome := meshes[1].(*Mesh)
+	if metal, ok := chrome.material.(Metal); !ok || metal.Fuzz > 0.1 {
+		t.Errorf("unexpected chrome material %v", chrome.material)
+	}
+
+	if glass := meshes[2].(*Mesh).material; glass != (Dielectric{1.3}) {
+		t.Errorf("unexpected glass material %v", glass)
+	}
+
+	// all meshes share the same vertex buffer
+	if &red.vertices[0] != &chrome.vertices[0] {
+		t.Errorf("vertex buffers are not shared")
+	}
+
+	hit, hr := meshes.Hit(&Ray{Origin: Point3{0.7, 0.2, 1}, Direction: Vec3{Z: -1}}, 0.001, math.MaxFloat64)
+	if !hit || hr.Normal.Z < 0.99 {
+		t.Errorf("expected hit with normal facing +Z")
+	}
+}
+
+func TestLoadOBJ_SmoothingGroups(t *testing.T) {
+	// a flat shaded face then a face in a (large) smoothing group: the vertices must not be shared
+	dir := writeTestFiles(t, map[string]string{
+		"model.obj": "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nf 1 2 3\ns 2147483649\nf 1 3 4\nf 1 4 2\n",
+	})
+	defer os.RemoveAll(dir)
+
+	meshes, err := LoadOBJ(filepath.Join(dir, "model.obj"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 3 vertices for the flat face and 4 shared by the 2 faces of the smoothing group
+	if mesh := meshes[0].(*Mesh); len(mesh.vertices) != 7 {
+		t.Errorf("expected 7 vertices got %v instead", len(mesh.vertices))
+	}
+}
+
+func TestLoadOBJ_MetalFuzz(t *testing.T) {
+	tests := []struct {
+		ns   string
+		fuzz float64
+	}{
+		{"", roughMetalFuzz},
+		{"Ns 0", roughMetalFuzz},
+		{"Ns 6", 0.5},
+	}
+
+	for idx, test := range tests {
+		dir := writeTestFiles(t, map[string]string{
+			"metal.mtl": "newmtl metal\nKd 0 0 0\nKs 0.9 0.9 0.9\n" + test.ns + "\n",
+			"metal.obj": "mtllib metal.mtl\nv 0 0 0\nv 1 0 0\nv 0 1 0\nusemtl metal\nf 1 2 3\n",
+		})
+		defer os.RemoveAll(dir)
+
+		meshes, err := LoadOBJ(filepath.Join(dir, "metal.obj"))
+		if err != nil {
+			t.Fatalf("%v [test %v]", err, idx)
+		}
+		metal, ok := meshes[0].(*Mesh).material.(Metal)
+		if !ok || !floatEquals(metal.Fuzz, test.fuzz) || metal.Fuzz >= 1 {
+			t.Errorf("metal with fuzz %v expected got %v instead [test %v]", test.fuzz, meshes[0].(*Mesh).material, idx)
+		}
+	}
+}
+
+func TestLoadOBJ_Errors(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"index.obj":    "v 0 0 0\nv 1 0 0\nv 1 1 0\n\nf 1 2 4\n",
+		"material.obj": "v 0 0 0\nusemtl unknown\n",
+		"number.obj":   "v 0 0 0\nv 1 x 0\n",
+		"smooth.obj":   "v 0 0 0\nv 1 0 0\nv 1 1 0\ns -1\nf 1 2 3\n",
+	})
+	defer os.RemoveAll(dir)
+
+	var tests = []struct {
+		file string
+		line int
+	}{
+		{"index.obj", 5},
+		{"material.obj", 2},
+		{"number.obj", 2},
+		{"smooth.obj", 4},
+	}
+
+	for _, test := range tests {
+		_, err := LoadOBJ(filepath.Join(dir, test.file))
+		pe, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("expected ParseError got %v instead [%v]", err, test.file)
+			continue
+		}
+		if pe.Line != test.line || pe.File != filepath.Join(dir, test.file) {
+			t.Errorf("expected error at line %v got %v instead [%v]", test.line, pe, test.file)
+		}
+	}
+}
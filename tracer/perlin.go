@@ -0,0 +1,160 @@
+package tracer
+
+import (
+	"fmt"
+	"math"
+)
+
+/***********************
+ * Perlin
+ ************************/
+// perlinSize is the number of (random) gradients used by the noise (must be a power of 2)
+const perlinSize = 256
+
+// Perlin generates (gradient) Perlin noise. The gradients and permutations are generated from the random number
+// generator provided so that the noise is reproducible for a given seed
+type Perlin struct {
+	gradients           [perlinSize]Vec3
+	permX, permY, permZ [perlinSize]int
+}
+
+// NewPerlin creates a noise generator using rnd as the source of randomness
+func NewPerlin(rnd Rnd) *Perlin {
+	p := &Perlin{}
+
+	for i := range p.gradients {
+		p.gradients[i] = Vec3{2.0*rnd.Float64() - 1.0, 2.0*rnd.Float64() - 1.0, 2.0*rnd.Float64() - 1.0}.Unit()
+	}
+
+	perlinPermutation(rnd, &p.permX)
+	perlinPermutation(rnd, &p.permY)
+	perlinPermutation(rnd, &p.permZ)
+
+	return p
+}
+
+// perlinPermutation fills perm with a random permutation of [0, perlinSize) (Fisher-Yates)
+func perlinPermutation(rnd Rnd, perm *[perlinSize]int) {
+	for i := range perm {
+		perm[i] = i
+	}
+	for i := len(perm) - 1; i > 0; i-- {
+		j := int(rnd.Float64() * float64(i+1))
+		perm[i], perm[j] = perm[j], perm[i]
+	}
+}
+
+// Noise returns the noise at point p (in [-1,1])
+func (perlin *Perlin) Noise(p Point3) float64 {
+	fi, fj, fk := math.Floor(p.X), math.Floor(p.Y), math.Floor(p.Z)
+	u, v, w := p.X-fi, p.Y-fj, p.Z-fk
+	i, j, k := int(fi), int(fj), int(fk)
+
+	var c [2][2][2]Vec3
+	for di := 0; di < 2; di++ {
+		for dj := 0; dj < 2; dj++ {
+			for dk := 0; dk < 2; dk++ {
+				c[di][dj][dk] = perlin.gradients[perlin.permX[(i+di)&(perlinSize-1)]^
+					perlin.permY[(j+dj)&(perlinSize-1)]^
+					perlin.permZ[(k+dk)&(perlinSize-1)]]
+			}
+		}
+	}
+
+	// hermite smoothing
+	uu := u * u * (3 - 2*u)
+	vv := v * v * (3 - 2*v)
+	ww := w * w * (3 - 2*w)
+
+	accum := 0.0
+	for di := 0; di < 2; di++ {
+		for dj := 0; dj < 2; dj++ {
+			for dk := 0; dk < 2; dk++ {
+				fdi, fdj, fdk := float64(di), float64(dj), float64(dk)
+				weight := Vec3{u - fdi, v - fdj, w - fdk}
+				accum += (fdi*uu + (1-fdi)*(1-uu)) *
+					(fdj*vv + (1-fdj)*(1-vv)) *
+					(fdk*ww + (1-fdk)*(1-ww)) *
+					Dot(c[di][dj][dk], weight)
+			}
+		}
+	}
+
+	return accum
+}
+
+// Turbulence sums octaves of noise (each one with double frequency and half amplitude)
+func (perlin *Perlin) Turbulence(p Point3, octaves int) float64 {
+	accum := 0.0
+	weight := 1.0
+	for i := 0; i < octaves; i++ {
+		accum += weight * perlin.Noise(p)
+		weight *= 0.5
+		p = Point3{p.X * 2, p.Y * 2, p.Z * 2}
+	}
+	return math.Abs(accum)
+}
+
+/***********************
+ * NoiseTexture
+ ************************/
+// NoisePattern defines how the noise is turned into a texture
+type NoisePattern int
+
+const (
+	// NoiseSmooth is the raw (smooth) noise
+	NoiseSmooth NoisePattern = iota
+	// NoiseTurbulence is the sum of octaves of noise (camouflage like)
+	NoiseTurbulence
+	// NoiseMarble is a sine wave along Z perturbed by turbulence (marble veins)
+	NoiseMarble
+	// NoiseWood is a set of concentric rings around the Y axis perturbed by turbulence (wood grain)
+	NoiseWood
+)
+
+var noisePatternNames = []string{"smooth", "turbulence", "marble", "wood"}
+
+func (np NoisePattern) String() string {
+	if np < 0 || int(np) >= len(noisePatternNames) {
+		return fmt.Sprintf("NoisePattern(%d)", int(np))
+	}
+	return noisePatternNames[np]
+}
+
+// NoiseTexture is a procedural texture blending between 2 colors based on a noise pattern
+//	scale defines the frequency of the pattern (bigger means more details)
+//	octaves is the number of octaves of noise used for the turbulence
+type NoiseTexture struct {
+	perlin  *Perlin
+	pattern NoisePattern
+	scale   float64
+	octaves int
+	c0, c1  Color
+}
+
+// NewNoiseTexture creates a procedural texture (c0 is used where the pattern is 0 and c1 where it is 1)
+func NewNoiseTexture(perlin *Perlin, pattern NoisePattern, scale float64, octaves int, c0 Color, c1 Color) NoiseTexture {
+	return NoiseTexture{perlin: perlin, pattern: pattern, scale: scale, octaves: octaves, c0: c0, c1: c1}
+}
+
+func (nt NoiseTexture) Value(u, v float64, p Point3) Color {
+	sp := Point3{p.X * nt.scale, p.Y * nt.scale, p.Z * nt.scale}
+
+	var t float64
+
+	switch nt.pattern {
+	case NoiseTurbulence:
+		t = nt.perlin.Turbulence(sp, nt.octaves)
+	case NoiseMarble:
+		t = 0.5 * (1 + math.Sin(sp.Z+10*nt.perlin.Turbulence(p, nt.octaves)))
+	case NoiseWood:
+		rings := math.Sqrt(sp.X*sp.X+sp.Z*sp.Z) + 2*nt.perlin.Turbulence(p, nt.octaves)
+		t = rings - math.Floor(rings)
+	default:
+		t = 0.5 * (1 + nt.perlin.Noise(sp))
+	}
+
+	t = math.Max(0, math.Min(1, t))
+
+	return nt.c0.Scale(1 - t).Add(nt.c1.Scale(t))
+}
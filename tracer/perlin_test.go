@@ -0,0 +1,98 @@
+package tracer
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// perlinTestPoints returns points spread over several cells of the noise (deterministic)
+func perlinTestPoints() []Point3 {
+	rnd := rand.New(rand.NewSource(1))
+	points := make([]Point3, 1000)
+	for i := range points {
+		points[i] = Point3{20*rnd.Float64() - 10, 20*rnd.Float64() - 10, 20*rnd.Float64() - 10}
+	}
+	return points
+}
+
+func TestPerlin_Seed(t *testing.T) {
+	p1 := NewPerlin(rand.New(rand.NewSource(2017)))
+	p2 := NewPerlin(rand.New(rand.NewSource(2017)))
+	p3 := NewPerlin(rand.New(rand.NewSource(2018)))
+
+	different := 0
+	for _, p := range perlinTestPoints() {
+		// same seed => same noise
+		if n1, n2 := p1.Noise(p), p2.Noise(p); n1 != n2 {
+			t.Fatalf("%v: %v expected got %v instead", p, n1, n2)
+		}
+		if p1.Noise(p) != p3.Noise(p) {
+			different++
+		}
+	}
+
+	// different seed => different noise
+	if different == 0 {
+		t.Errorf("a different seed should produce a different noise")
+	}
+}
+
+func TestPerlin_Ranges(t *testing.T) {
+	perlin := NewPerlin(rand.New(rand.NewSource(2017)))
+
+	// gradient noise is 0 on the lattice
+	for _, p := range []Point3{{}, {1, 2, 3}, {-4, 5, -6}} {
+		if n := perlin.Noise(p); !floatEquals(n, 0) {
+			t.Errorf("%v: 0 expected got %v instead", p, n)
+		}
+	}
+
+	var tests = []struct {
+		octaves int
+		max     float64
+	}{
+		{1, 1},
+		{4, 1 + 0.5 + 0.25 + 0.125},
+		{7, 2},
+	}
+
+	for _, p := range perlinTestPoints() {
+		if n := perlin.Noise(p); n < -1 || n > 1 {
+			t.Errorf("%v: noise %v not in [-1,1]", p, n)
+		}
+		for idx, test := range tests {
+			if turbulence := perlin.Turbulence(p, test.octaves); turbulence < 0 || turbulence > test.max {
+				t.Errorf("%v: turbulence %v not in [0,%v] [test %v]", p, turbulence, test.max, idx)
+			}
+		}
+	}
+}
+
+func TestNoiseTexture(t *testing.T) {
+	perlin := NewPerlin(rand.New(rand.NewSource(2017)))
+
+	for _, pattern := range []NoisePattern{NoiseSmooth, NoiseTurbulence, NoiseMarble, NoiseWood} {
+		// with black and white, the value is the pattern itself which must be in [0,1]
+		texture := NewNoiseTexture(perlin, pattern, 4, 7, Black, White)
+		min, max := math.Inf(1), math.Inf(-1)
+		for _, p := range perlinTestPoints() {
+			c := texture.Value(0, 0, p)
+			if c.R != c.G || c.G != c.B {
+				t.Fatalf("%v: gray expected got %v instead", pattern, c)
+			}
+			min, max = math.Min(min, c.R), math.Max(max, c.R)
+		}
+		if min < 0 || max > 1 {
+			t.Errorf("%v: [%v,%v] not in [0,1]", pattern, min, max)
+		}
+		// the pattern is not constant
+		if max-min < 0.1 {
+			t.Errorf("%v: [%v,%v] is too narrow", pattern, min, max)
+		}
+	}
+
+	if NoiseMarble.String() != "marble" || NoisePattern(10).String() != "NoisePattern(10)" {
+		t.Errorf("unexpected names %v/%v", NoiseMarble, NoisePattern(10))
+	}
+}
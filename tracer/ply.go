@@ -0,0 +1,405 @@
+package tracer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/***********************
+ * PLY
+ ************************/
+// plyType is the type of a property value
+type plyType int
+
+const (
+	plyInt8 plyType = iota
+	plyUint8
+	plyInt16
+	plyUint16
+	plyInt32
+	plyUint32
+	plyFloat32
+	plyFloat64
+)
+
+var plyTypes = map[string]plyType{
+	"char": plyInt8, "int8": plyInt8,
+	"uchar": plyUint8, "uint8": plyUint8,
+	"short": plyInt16, "int16": plyInt16,
+	"ushort": plyUint16, "uint16": plyUint16,
+	"int": plyInt32, "int32": plyInt32,
+	"uint": plyUint32, "uint32": plyUint32,
+	"float": plyFloat32, "float32": plyFloat32,
+	"double": plyFloat64, "float64": plyFloat64,
+}
+
+// plyProperty is a property of an element (list properties have a count followed by count values)
+type plyProperty struct {
+	name      string
+	kind      plyType
+	isList    bool
+	countKind plyType
+}
+
+type plyElement struct {
+	name       string
+	count      int
+	properties []plyProperty
+}
+
+// plyReader abstracts the ascii and binary encodings
+type plyReader interface {
+	// startElement is called before reading the properties of each element
+	startElement() error
+	value(kind plyType) (float64, error)
+	// location returns a description of the current location in the file (for errors)
+	location() (int, string)
+}
+
+// LoadPLY loads a PLY file (ascii, binary little or big endian) as a mesh
+//	vertex properties x/y/z (position), nx/ny/nz (normal) and red/green/blue (color) are used
+//	face property vertex_indices (or vertex_index) defines the polygons (triangulated as a fan)
+// When the vertices have colors, they feed the albedo of the mesh (see Mesh), otherwise the mesh uses a gray
+// Lambertian material. Colors are assumed to be sRGB encoded (like textures)
+func LoadPLY(path string) (*Mesh, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+
+	// header
+	line := 0
+	readLine := func() ([]string, error) {
+		s, err := r.ReadString('\n')
+		if err != nil {
+			return nil, &ParseError{path, line + 1, fmt.Errorf("unexpected end of header")}
+		}
+		line++
+		return strings.Fields(s), nil
+	}
+
+	fields, err := readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) != 1 || fields[0] != "ply" {
+		return nil, &ParseError{path, line, fmt.Errorf("not a ply file (missing ply signature)")}
+	}
+
+	var format string
+	var elements []*plyElement
+
+	for {
+		fields, err := readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "end_header" {
+			break
+		}
+
+		switch fields[0] {
+		case "format":
+			if len(fields) != 3 {
+				return nil, &ParseError{path, line, fmt.Errorf("invalid format")}
+			}
+			format = fields[1]
+			if format != "ascii" && format != "binary_little_endian" && format != "binary_big_endian" {
+				return nil, &ParseError{path, line, fmt.Errorf("unsupported format [%v]", format)}
+			}
+		case "element":
+			if len(fields) != 3 {
+				return nil, &ParseError{path, line, fmt.Errorf("invalid element")}
+			}
+			count, err := strconv.Atoi(fields[2])
+			if err != nil || count < 0 {
+				return nil, &ParseError{path, line, fmt.Errorf("invalid element count [%v]", fields[2])}
+			}
+			elements = append(elements, &plyElement{name: fields[1], count: count})
+		case "property":
+			if len(elements) == 0 {
+				return nil, &ParseError{path, line, fmt.Errorf("property before element")}
+			}
+			property, err := parsePLYProperty(fields[1:])
+			if err != nil {
+				return nil, &ParseError{path, line, err}
+			}
+			element := elements[len(elements)-1]
+			element.properties = append(element.properties, property)
+		case "comment", "obj_info":
+		default:
+			return nil, &ParseError{path, line, fmt.Errorf("unknown header keyword [%v]", fields[0])}
+		}
+	}
+
+	if format == "" {
+		return nil, &ParseError{path, line, fmt.Errorf("missing format")}
+	}
+
+	var reader plyReader
+	switch format {
+	case "ascii":
+		reader = &plyASCIIReader{r: r, line: line}
+	case "binary_little_endian":
+		reader = &plyBinaryReader{r: r, order: binary.LittleEndian}
+	default:
+		reader = &plyBinaryReader{r: r, order: binary.BigEndian}
+	}
+
+	mesh, err := readPLYElements(reader, elements)
+	if err != nil {
+		line, description := reader.location()
+		if line > 0 {
+			return nil, &ParseError{path, line, err}
+		}
+		return nil, fmt.Errorf("%v: %v: %v", path, description, err)
+	}
+
+	return mesh, nil
+}
+
+func parsePLYProperty(fields []string) (plyProperty, error) {
+	if len(fields) == 4 && fields[0] == "list" {
+		countKind, ok := plyTypes[fields[1]]
+		if !ok {
+			return plyProperty{}, fmt.Errorf("unknown type [%v]", fields[1])
+		}
+		kind, ok := plyTypes[fields[2]]
+		if !ok {
+			return plyProperty{}, fmt.Errorf("unknown type [%v]", fields[2])
+		}
+		return plyProperty{name: fields[3], kind: kind, isList: true, countKind: countKind}, nil
+	}
+
+	if len(fields) != 2 {
+		return plyProperty{}, fmt.Errorf("invalid property")
+	}
+	kind, ok := plyTypes[fields[0]]
+	if !ok {
+		return plyProperty{}, fmt.Errorf("unknown type [%v]", fields[0])
+	}
+	return plyProperty{name: fields[1], kind: kind}, nil
+}
+
+// readPLYElements reads the data of all the elements (elements other than vertex and face are skipped)
+func readPLYElements(reader plyReader, elements []*plyElement) (*Mesh, error) {
+	var vertices []Point3
+	var normals []Vec3
+	var colors []Color
+	var indices []int
+
+	for _, element := range elements {
+		// index of each property in the element (-1 if not present)
+		index := func(names ...string) int {
+			for i, property := range element.properties {
+				for _, name := range names {
+					if property.name == name && !property.isList {
+						return i
+					}
+				}
+			}
+			return -1
+		}
+
+		x, y, z := index("x"), index("y"), index("z")
+		nx, ny, nz := index("nx"), index("ny"), index("nz")
+		red, green, blue := index("red", "r", "diffuse_red"), index("green", "g", "diffuse_green"), index("blue", "b", "diffuse_blue")
+
+		isVertex := element.name == "vertex"
+		hasNormals := isVertex && nx >= 0 && ny >= 0 && nz >= 0
+		hasColors := isVertex && red >= 0 && green >= 0 && blue >= 0
+		if isVertex && (x < 0 || y < 0 || z < 0) {
+			return nil, fmt.Errorf("vertex element without x/y/z properties")
+		}
+
+		values := make([]float64, len(element.properties))
+
+		for i := 0; i < element.count; i++ {
+			if err := reader.startElement(); err != nil {
+				return nil, err
+			}
+
+			var polygon []int
+
+			for p, property := range element.properties {
+				if !property.isList {
+					v, err := reader.value(property.kind)
+					if err != nil {
+						return nil, err
+					}
+					values[p] = v
+					continue
+				}
+
+				count, err := reader.value(property.countKind)
+				if err != nil {
+					return nil, err
+				}
+				isPolygon := element.name == "face" && (property.name == "vertex_indices" || property.name == "vertex_index")
+				for c := 0; c < int(count); c++ {
+					v, err := reader.value(property.kind)
+					if err != nil {
+						return nil, err
+					}
+					if isPolygon {
+						polygon = append(polygon, int(v))
+					}
+				}
+			}
+
+			if isVertex {
+				vertices = append(vertices, Point3{values[x], values[y], values[z]})
+				if hasNormals {
+					normals = append(normals, Vec3{values[nx], values[ny], values[nz]}.Unit())
+				}
+				if hasColors {
+					c := Color{
+						R: plyColorComponent(values[red], element.properties[red].kind),
+						G: plyColorComponent(values[green], element.properties[green].kind),
+						B: plyColorComponent(values[blue], element.properties[blue].kind),
+					}
+					colors = append(colors, SRGBToLinear(c))
+				}
+			}
+
+			if len(polygon) > 0 {
+				if len(polygon) < 3 {
+					return nil, fmt.Errorf("face with less than 3 vertices")
+				}
+				for _, vi := range polygon {
+					if vi < 0 || vi >= len(vertices) {
+						return nil, fmt.Errorf("vertex index %v out of range (%v vertices)", vi, len(vertices))
+					}
+				}
+				// triangulate (fan)
+				for t := 1; t < len(polygon)-1; t++ {
+					indices = append(indices, polygon[0], polygon[t], polygon[t+1])
+				}
+			}
+		}
+	}
+
+	if colors != nil {
+		return NewColoredMesh(vertices, normals, colors, indices), nil
+	}
+
+	return NewMesh(vertices, normals, nil, indices, defaultModelMaterial), nil
+}
+
+// plyColorComponent converts a color component into [0,1] (integer components are in [0,max])
+func plyColorComponent(v float64, kind plyType) float64 {
+	switch kind {
+	case plyUint8:
+		return v / 255.0
+	case plyUint16:
+		return v / 65535.0
+	}
+	return v
+}
+
+// plyASCIIReader reads an ascii ply file (one element per line)
+type plyASCIIReader struct {
+	r      *bufio.Reader
+	line   int
+	fields []string
+}
+
+func (ar *plyASCIIReader) startElement() error {
+	if len(ar.fields) > 0 {
+		return fmt.Errorf("unexpected values [%v]", strings.Join(ar.fields, " "))
+	}
+	for {
+		s, err := ar.r.ReadString('\n')
+		if err != nil && (err != io.EOF || s == "") {
+			return fmt.Errorf("unexpected end of file")
+		}
+		ar.line++
+		ar.fields = strings.Fields(s)
+		if len(ar.fields) > 0 {
+			return nil
+		}
+	}
+}
+
+func (ar *plyASCIIReader) value(kind plyType) (float64, error) {
+	if len(ar.fields) == 0 {
+		return 0, fmt.Errorf("missing value")
+	}
+	field := ar.fields[0]
+	ar.fields = ar.fields[1:]
+	v, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number [%v]", field)
+	}
+	return v, nil
+}
+
+func (ar *plyASCIIReader) location() (int, string) {
+	return ar.line, ""
+}
+
+// plyBinaryReader reads a binary ply file
+type plyBinaryReader struct {
+	r      *bufio.Reader
+	order  binary.ByteOrder
+	offset int64
+	buf    [8]byte
+}
+
+func (br *plyBinaryReader) startElement() error {
+	return nil
+}
+
+func (br *plyBinaryReader) value(kind plyType) (float64, error) {
+	size := 1
+	switch kind {
+	case plyInt16, plyUint16:
+		size = 2
+	case plyInt32, plyUint32, plyFloat32:
+		size = 4
+	case plyFloat64:
+		size = 8
+	}
+
+	b := br.buf[:size]
+	if _, err := io.ReadFull(br.r, b); err != nil {
+		return 0, fmt.Errorf("unexpected end of file")
+	}
+	br.offset += int64(size)
+
+	switch kind {
+	case plyInt8:
+		return float64(int8(b[0])), nil
+	case plyUint8:
+		return float64(b[0]), nil
+	case plyInt16:
+		return float64(int16(br.order.Uint16(b))), nil
+	case plyUint16:
+		return float64(br.order.Uint16(b)), nil
+	case plyInt32:
+		return float64(int32(br.order.Uint32(b))), nil
+	case plyUint32:
+		return float64(br.order.Uint32(b)), nil
+	case plyFloat32:
+		return float64(math.Float32frombits(br.order.Uint32(b))), nil
+	default:
+		return math.Float64frombits(br.order.Uint64(b)), nil
+	}
+}
+
+func (br *plyBinaryReader) location() (int, string) {
+	return 0, fmt.Sprintf("data offset %v", br.offset)
+}
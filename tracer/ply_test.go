@@ -0,0 +1,96 @@
+package tracer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPLY_ASCII(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"quad.ply": `ply
+format ascii 1.0
+comment a colored quad
+element vertex 4
+property float x
+property float y
+property float z
+property uchar red
+property uchar green
+property uchar blue
+element face 1
+property list uchar int vertex_indices
+end_header
+0 0 0 255 0 0
+1 0 0 255 0 0
+1 1 0 255 0 0
+0 1 0 255 0 0
+4 0 1 2 3
+`,
+		"error.ply": "ply\nformat ascii 1.0\nelement vertex 1\nproperty float x\nproperty float y\nproperty float z\nend_header\n0 x 0\n",
+	})
+	defer os.RemoveAll(dir)
+
+	mesh, err := LoadPLY(filepath.Join(dir, "quad.ply"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mesh.TriangleCount() != 2 || len(mesh.vertices) != 4 {
+		t.Fatalf("unexpected mesh %v triangles %v vertices", mesh.TriangleCount(), len(mesh.vertices))
+	}
+
+	// vertex colors feed the albedo
+	hit, hr := mesh.Hit(&Ray{Origin: Point3{0.5, 0.5, 1}, Direction: Vec3{Z: -1}}, 0.001, math.MaxFloat64)
+	if !hit || hr.Material != (vertexColorMaterial{}) || hr.Material.(diffuseMaterial).diffuseAlbedo(hr) != (Color{R: 1}) {
+		t.Errorf("expected red lambertian got %v instead", hr)
+	}
+
+	// no material is created per hit (the only allocation is the hit record)
+	plain := NewMesh(mesh.vertices, nil, nil, mesh.indices, Lambertian{White})
+	hitAllocs := func(m *Mesh) float64 {
+		return testing.AllocsPerRun(100, func() {
+			m.Hit(&Ray{Origin: Point3{0.5, 0.5, 1}, Direction: Vec3{Z: -1}}, 0.001, math.MaxFloat64)
+		})
+	}
+	if colored, expected := hitAllocs(mesh), hitAllocs(plain); colored != expected {
+		t.Errorf("%v allocations expected got %v instead", expected, colored)
+	}
+
+	_, err = LoadPLY(filepath.Join(dir, "error.ply"))
+	if pe, ok := err.(*ParseError); !ok || pe.Line != 8 {
+		t.Errorf("expected error at line 8 got %v instead", err)
+	}
+}
+
+func TestLoadPLY_Binary(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("ply\nformat binary_big_endian 1.0\nelement vertex 3\nproperty double x\nproperty double y\nproperty double z\nelement face 1\nproperty list uchar uint vertex_index\nend_header\n")
+	for _, v := range []float64{0, 0, 0, 1, 0, 0, 0, 1, 0} {
+		binary.Write(&buf, binary.BigEndian, v)
+	}
+	buf.WriteByte(3)
+	for _, i := range []uint32{0, 1, 2} {
+		binary.Write(&buf, binary.BigEndian, i)
+	}
+
+	dir := writeTestFiles(t, map[string]string{})
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "triangle.ply")
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mesh, err := LoadPLY(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mesh.TriangleCount() != 1 || mesh.vertices[1] != (Point3{1, 0, 0}) || mesh.colors != nil {
+		t.Errorf("unexpected mesh %v", mesh.vertices)
+	}
+}
@@ -0,0 +1,129 @@
+package tracer
+
+import (
+	"fmt"
+	"time"
+)
+
+// ProgressKind defines what happened when a ProgressEvent is emitted
+type ProgressKind int
+
+const (
+	// PassStarted is emitted before a pass (one entry of Scene.RaysPerPixel) starts
+	PassStarted ProgressKind = iota
+	// PassFinished is emitted when every pixel of the pass has been rendered
+	PassFinished
+	// RenderCompleted is emitted once, when all the passes are done
+	RenderCompleted
+	// RenderCancelled is emitted once, when the rendering stops because the context is done
+	RenderCancelled
+)
+
+var progressKindNames = []string{"pass-started", "pass-finished", "render-completed", "render-cancelled"}
+
+func (k ProgressKind) String() string {
+	if k < 0 || int(k) >= len(progressKindNames) {
+		return fmt.Sprintf("ProgressKind(%d)", int(k))
+	}
+	return progressKindNames[k]
+}
+
+// ProgressEvent describes the progress of a rendering (see Scene.RenderContext)
+//	Pass is the index (in Scene.RaysPerPixel) of the pass and RaysPerPixel the number of rays per pixel of this pass
+//	(for RenderCompleted it is the last pass and for RenderCancelled the pass which was interrupted)
+//	AccumulatedRaysPerPixel is the number of rays per pixel cast so far (all passes finished) out of TotalRaysPerPixel
+//	PassElapsed is the time spent on the pass (PassFinished only) and Elapsed since the rendering started
+//	ETA is the estimated remaining time and RaysPerSecond the number of (primary) rays cast per second so far
+//	(both are 0 until the first pass is finished)
+type ProgressEvent struct {
+	Kind                    ProgressKind
+	Pass                    int
+	Passes                  int
+	RaysPerPixel            int
+	AccumulatedRaysPerPixel int
+	TotalRaysPerPixel       int
+	PassElapsed             time.Duration
+	Elapsed                 time.Duration
+	ETA                     time.Duration
+	RaysPerSecond           float64
+}
+
+// ProgressFunc is called with each event of a rendering. It is called from the goroutine coordinating the
+// rendering (never concurrently) and should return quickly since the next pass does not start before it returns.
+type ProgressFunc func(event ProgressEvent)
+
+func (e ProgressEvent) String() string {
+	switch e.Kind {
+	case PassStarted:
+		return fmt.Sprintf("Processing pass %v/%v (%v rays per pixel)", e.Pass+1, e.Passes, e.RaysPerPixel)
+	case PassFinished:
+		return fmt.Sprintf("Processed %v rays per pixel in %v. Total %v in %v. ERM %v", e.RaysPerPixel, e.PassElapsed, e.AccumulatedRaysPerPixel, e.Elapsed, e.ETA)
+	case RenderCompleted:
+		return fmt.Sprintf("Render complete (%v rays per pixel in %v, %.0f rays/s)", e.AccumulatedRaysPerPixel, e.Elapsed, e.RaysPerSecond)
+	case RenderCancelled:
+		return fmt.Sprintf("Render cancelled (%v/%v rays per pixel in %v)", e.AccumulatedRaysPerPixel, e.TotalRaysPerPixel, e.Elapsed)
+	}
+	return e.Kind.String()
+}
+
+// progressTracker computes the events (timing and estimates) of a rendering
+type progressTracker struct {
+	width, height int
+	passes        []int
+	total         int
+	accumulated   int
+	start         time.Time
+	passStart     time.Time
+	callback      ProgressFunc
+}
+
+func newProgressTracker(scene *Scene, callback ProgressFunc) *progressTracker {
+	total := 0
+	for _, rpp := range scene.RaysPerPixel {
+		total += rpp
+	}
+	now := time.Now()
+	return &progressTracker{width: scene.Width, height: scene.Height, passes: scene.RaysPerPixel, total: total, start: now, passStart: now, callback: callback}
+}
+
+// emit computes the event and calls the callback (if any)
+func (pt *progressTracker) emit(kind ProgressKind, pass int) {
+	now := time.Now()
+
+	switch kind {
+	case PassStarted:
+		pt.passStart = now
+	case PassFinished:
+		pt.accumulated += pt.passes[pass]
+	}
+
+	event := ProgressEvent{
+		Kind:                    kind,
+		Pass:                    pass,
+		Passes:                  len(pt.passes),
+		AccumulatedRaysPerPixel: pt.accumulated,
+		TotalRaysPerPixel:       pt.total,
+		Elapsed:                 now.Sub(pt.start),
+	}
+
+	if pass >= 0 && pass < len(pt.passes) {
+		event.RaysPerPixel = pt.passes[pass]
+	}
+
+	if kind == PassFinished {
+		event.PassElapsed = now.Sub(pt.passStart)
+	}
+
+	// estimates are based on the passes finished so far
+	if pt.accumulated > 0 {
+		estimatedTotalTime := time.Duration(float64(event.Elapsed) * float64(pt.total) / float64(pt.accumulated))
+		event.ETA = estimatedTotalTime - event.Elapsed
+		if event.Elapsed > 0 {
+			event.RaysPerSecond = float64(pt.width*pt.height*pt.accumulated) / event.Elapsed.Seconds()
+		}
+	}
+
+	if pt.callback != nil {
+		pt.callback(event)
+	}
+}
@@ -0,0 +1,46 @@
+package tracer
+
+// pixelRnd is a small non synchronized random number generator (splitmix64) used while rendering. Its stream is
+// derived from the seed of the scene, the coordinates of the pixel and the pass (see reset) so that the color of a
+// pixel does not depend on which goroutine renders it (nor on how many goroutines there are): a given seed always
+// produces the same image.
+type pixelRnd struct {
+	state uint64
+}
+
+const splitmix64Increment = 0x9E3779B97F4A7C15
+
+// mix64 is the finalizer of splitmix64 (a bijection which thoroughly mixes the bits of z)
+func mix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
+
+// newPixelRnd creates the generator for the pixel x/y during pass
+func newPixelRnd(seed int64, x, y, pass int) *pixelRnd {
+	r := &pixelRnd{}
+	r.reset(seed, x, y, pass)
+	return r
+}
+
+// reset restarts the generator with the stream of the pixel x/y during pass (avoids allocating a generator per pixel)
+func (r *pixelRnd) reset(seed int64, x, y, pass int) {
+	// each value is mixed in turn (so that x/y and y/x produce different streams)
+	h := mix64(uint64(seed) + splitmix64Increment)
+	h = mix64(h + uint64(x) + splitmix64Increment)
+	h = mix64(h + uint64(y) + splitmix64Increment)
+	h = mix64(h + uint64(pass) + splitmix64Increment)
+	r.state = h
+}
+
+// Uint64 returns the next 64 bits of the stream
+func (r *pixelRnd) Uint64() uint64 {
+	r.state += splitmix64Increment
+	return mix64(r.state)
+}
+
+// Float64 returns a number in [0,1) (53 bits of precision)
+func (r *pixelRnd) Float64() float64 {
+	return float64(r.Uint64()>>11) / (1 << 53)
+}
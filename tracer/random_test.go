@@ -0,0 +1,50 @@
+package tracer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPixelRnd(t *testing.T) {
+	// same pixel/pass => same stream
+	r1, r2 := newPixelRnd(2017, 10, 20, 1), newPixelRnd(2017, 10, 20, 1)
+	for i := 0; i < 10; i++ {
+		if a, b := r1.Float64(), r2.Float64(); a != b {
+			t.Errorf("%v expected got %v instead", a, b)
+		}
+	}
+
+	// different pixels/passes/seeds => different streams
+	var tests = []struct {
+		seed       int64
+		x, y, pass int
+	}{
+		{2018, 10, 20, 1},
+		{2017, 11, 20, 1},
+		{2017, 10, 21, 1},
+		{2017, 10, 20, 2},
+		{2017, 20, 10, 1},
+	}
+
+	first := newPixelRnd(2017, 10, 20, 1).Float64()
+	for idx, test := range tests {
+		if newPixelRnd(test.seed, test.x, test.y, test.pass).Float64() == first {
+			t.Errorf("different stream expected [test %v]", idx)
+		}
+	}
+
+	// uniform in [0,1)
+	r := newPixelRnd(0, 0, 0, 0)
+	n := 100000
+	sum := 0.0
+	for i := 0; i < n; i++ {
+		f := r.Float64()
+		if f < 0 || f >= 1 {
+			t.Fatalf("%v out of range", f)
+		}
+		sum += f
+	}
+	if mean := sum / float64(n); math.Abs(mean-0.5) > 0.01 {
+		t.Errorf("mean 0.5 expected got %v instead", mean)
+	}
+}
@@ -0,0 +1,134 @@
+package tracer
+
+// rectThickness is used to give some thickness to the bounding box of a rectangle (otherwise 0 along one axis)
+const rectThickness = 0.0001
+
+/***********************
+ * XYRect
+ ************************/
+// XYRect is an axis aligned rectangle in the plane z = k (normal pointing toward +Z)
+type XYRect struct {
+	X0, X1, Y0, Y1, K float64
+	Material          Material
+}
+
+// Hit implements the Hitable interface for a XYRect
+func (rect XYRect) Hit(r *Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	t := (rect.K - r.Origin.Z) / r.Direction.Z
+	if t < tMin || t > tMax {
+		return false, nil
+	}
+
+	p := r.PointAt(t)
+	if p.X < rect.X0 || p.X > rect.X1 || p.Y < rect.Y0 || p.Y > rect.Y1 {
+		return false, nil
+	}
+
+	u := (p.X - rect.X0) / (rect.X1 - rect.X0)
+	v := (p.Y - rect.Y0) / (rect.Y1 - rect.Y0)
+	return true, &HitRecord{T: t, P: p, Normal: Vec3{Z: 1.0}, Material: rect.Material, U: u, V: v}
+}
+
+// BoundingBox implements the Hitable interface for a XYRect
+func (rect XYRect) BoundingBox() (bool, *AABB) {
+	return true, &AABB{Point3{rect.X0, rect.Y0, rect.K - rectThickness}, Point3{rect.X1, rect.Y1, rect.K + rectThickness}}
+}
+
+/***********************
+ * XZRect
+ ************************/
+// XZRect is an axis aligned rectangle in the plane y = k (normal pointing toward +Y)
+type XZRect struct {
+	X0, X1, Z0, Z1, K float64
+	Material          Material
+}
+
+// Hit implements the Hitable interface for a XZRect
+func (rect XZRect) Hit(r *Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	t := (rect.K - r.Origin.Y) / r.Direction.Y
+	if t < tMin || t > tMax {
+		return false, nil
+	}
+
+	p := r.PointAt(t)
+	if p.X < rect.X0 || p.X > rect.X1 || p.Z < rect.Z0 || p.Z > rect.Z1 {
+		return false, nil
+	}
+
+	u := (p.X - rect.X0) / (rect.X1 - rect.X0)
+	v := (p.Z - rect.Z0) / (rect.Z1 - rect.Z0)
+	return true, &HitRecord{T: t, P: p, Normal: Vec3{Y: 1.0}, Material: rect.Material, U: u, V: v}
+}
+
+// BoundingBox implements the Hitable interface for a XZRect
+func (rect XZRect) BoundingBox() (bool, *AABB) {
+	return true, &AABB{Point3{rect.X0, rect.K - rectThickness, rect.Z0}, Point3{rect.X1, rect.K + rectThickness, rect.Z1}}
+}
+
+/***********************
+ * YZRect
+ ************************/
+// YZRect is an axis aligned rectangle in the plane x = k (normal pointing toward +X)
+type YZRect struct {
+	Y0, Y1, Z0, Z1, K float64
+	Material          Material
+}
+
+// Hit implements the Hitable interface for a YZRect
+func (rect YZRect) Hit(r *Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	t := (rect.K - r.Origin.X) / r.Direction.X
+	if t < tMin || t > tMax {
+		return false, nil
+	}
+
+	p := r.PointAt(t)
+	if p.Y < rect.Y0 || p.Y > rect.Y1 || p.Z < rect.Z0 || p.Z > rect.Z1 {
+		return false, nil
+	}
+
+	u := (p.Y - rect.Y0) / (rect.Y1 - rect.Y0)
+	v := (p.Z - rect.Z0) / (rect.Z1 - rect.Z0)
+	return true, &HitRecord{T: t, P: p, Normal: Vec3{X: 1.0}, Material: rect.Material, U: u, V: v}
+}
+
+// BoundingBox implements the Hitable interface for a YZRect
+func (rect YZRect) BoundingBox() (bool, *AABB) {
+	return true, &AABB{Point3{rect.K - rectThickness, rect.Y0, rect.Z0}, Point3{rect.K + rectThickness, rect.Y1, rect.Z1}}
+}
+
+/***********************
+ * FlipNormals
+ ************************/
+// FlipNormals wraps a hitable and reverses its normal (used to make a rectangle face the other way)
+type FlipNormals struct {
+	Hitable Hitable
+}
+
+// Hit implements the Hitable interface for FlipNormals
+func (fn FlipNormals) Hit(r *Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	hit, hr := fn.Hitable.Hit(r, tMin, tMax)
+	if hit {
+		hr.Normal = hr.Normal.Negate()
+	}
+	return hit, hr
+}
+
+// BoundingBox implements the Hitable interface for FlipNormals
+func (fn FlipNormals) BoundingBox() (bool, *AABB) {
+	return fn.Hitable.BoundingBox()
+}
+
+/***********************
+ * Box
+ ************************/
+// NewBox creates an axis aligned box (made of 6 rectangles with normals pointing outside) between the 2 corners
+func NewBox(p0 Point3, p1 Point3, material Material) HitableList {
+	return HitableList{
+		XYRect{p0.X, p1.X, p0.Y, p1.Y, p1.Z, material},
+		FlipNormals{XYRect{p0.X, p1.X, p0.Y, p1.Y, p0.Z, material}},
+		XZRect{p0.X, p1.X, p0.Z, p1.Z, p1.Y, material},
+		FlipNormals{XZRect{p0.X, p1.X, p0.Z, p1.Z, p0.Y, material}},
+		YZRect{p0.Y, p1.Y, p0.Z, p1.Z, p1.X, material},
+		FlipNormals{YZRect{p0.Y, p1.Y, p0.Z, p1.Z, p0.X, material}},
+	}
+}
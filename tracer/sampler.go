@@ -0,0 +1,548 @@
+package tracer
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+	"sync"
+)
+
+/***********************
+ * Sampler
+ ************************/
+// Sampler provides the samples (numbers in [0,1)) used to render a pixel. Unlike a plain Rnd, a sampler knows which
+// dimension each number is used for: the first 2D sample of a ray is the position in the pixel, the second one the
+// position on the lens (see RandomInUnitDisk), then come the samples used by the materials at each bounce. Samplers
+// other than SamplerIndependent distribute the values of each dimension evenly across the samples of a pixel (they
+// are not independent) which converges faster. Float64 (see Rnd) is the same as Get1D so that a sampler can be used
+// wherever a Rnd is expected (see Ray.Rnd).
+//	StartPixel must be called before the samples of the pixel x/y for pass are computed
+//	StartSample must be called before each sample (index counts the samples of the pixel across all passes) and
+//	resets the dimension
+type Sampler interface {
+	Rnd
+	StartPixel(x, y, pass int)
+	StartSample(index int)
+	Get1D() float64
+	Get2D() (float64, float64)
+}
+
+// SamplerType defines which kind of sampler is used to render a scene (see NewSampler)
+type SamplerType int
+
+const (
+	// SamplerIndependent uses independent random numbers (the original behavior of the book)
+	SamplerIndependent SamplerType = iota
+	// SamplerStratified splits each dimension (or pair of dimensions) in as many strata as there are samples in the
+	// pixel and jitters one sample in each stratum
+	SamplerStratified
+	// SamplerHalton uses the Halton sequence (one prime base per dimension) Owen scrambled for each pixel
+	SamplerHalton
+	// SamplerSobol uses the 2D Sobol sequence (Owen scrambled) for each pair of dimensions
+	SamplerSobol
+	// SamplerBlueNoise uses the same Owen scrambled Sobol points for every pixel shifted by a blue noise mask so
+	// that the error is distributed as blue noise across the pixels (less visible at low sample counts)
+	SamplerBlueNoise
+)
+
+var samplerTypeNames = []string{"independent", "stratified", "halton", "sobol", "blue-noise"}
+
+func (t SamplerType) String() string {
+	if t < 0 || int(t) >= len(samplerTypeNames) {
+		return fmt.Sprintf("SamplerType(%d)", int(t))
+	}
+	return samplerTypeNames[t]
+}
+
+// Set allows SamplerType to be used on the command line (flag)
+// Example: ray-tracing -sampler sobol
+func (t *SamplerType) Set(value string) error {
+	for i, name := range samplerTypeNames {
+		if value == name {
+			*t = SamplerType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown sampler [%v] (must be one of %v)", value, strings.Join(samplerTypeNames, ", "))
+}
+
+// NewSampler creates a sampler (which is not safe to use from multiple goroutines). The samples only depend on the
+// seed, the pixel, the index of the sample and the dimension so that a given seed always produces the same image.
+// samplesPerPixel is the total number of samples of each pixel (used by SamplerStratified to define the strata)
+func NewSampler(t SamplerType, seed int64, samplesPerPixel int) Sampler {
+	var pattern samplePattern
+	switch t {
+	case SamplerStratified:
+		pattern = stratifiedPattern{samplesPerPixel: maxInt(samplesPerPixel, 1)}
+	case SamplerHalton:
+		pattern = haltonPattern{}
+	case SamplerSobol:
+		pattern = sobolPattern{}
+	case SamplerBlueNoise:
+		pattern = blueNoisePattern{mask: blueNoiseMask()}
+	default:
+		return &independentSampler{seed: seed}
+	}
+	return &patternSampler{pattern: pattern, seed: seed}
+}
+
+// sample2D returns a 2D sample: an aligned pair of dimensions when rnd is a Sampler, 2 numbers otherwise
+func sample2D(rnd Rnd) (float64, float64) {
+	if s, ok := rnd.(Sampler); ok {
+		return s.Get2D()
+	}
+	return rnd.Float64(), rnd.Float64()
+}
+
+/***********************
+ * independentSampler
+ ************************/
+// independentSampler ignores the dimensions and returns the stream of random numbers of the pixel for the pass
+// (see pixelRnd)
+type independentSampler struct {
+	seed int64
+	rnd  pixelRnd
+}
+
+func (s *independentSampler) StartPixel(x, y, pass int) {
+	s.rnd.reset(s.seed, x, y, pass)
+}
+
+func (s *independentSampler) StartSample(index int) {}
+
+func (s *independentSampler) Float64() float64 {
+	return s.rnd.Float64()
+}
+
+func (s *independentSampler) Get1D() float64 {
+	return s.rnd.Float64()
+}
+
+func (s *independentSampler) Get2D() (float64, float64) {
+	return s.rnd.Float64(), s.rnd.Float64()
+}
+
+/***********************
+ * patternSampler
+ ************************/
+// samplePattern computes the sample of a dimension (or pair of dimensions for sample2D) from its coordinates
+type samplePattern interface {
+	sample1D(c sampleCoordinates) float64
+	sample2D(c sampleCoordinates) (float64, float64)
+}
+
+// sampleCoordinates identifies a sample: the pixel x/y (along with its hash which includes the seed), the index of
+// the sample in the pixel and the (first) dimension
+type sampleCoordinates struct {
+	seed, pixel      uint64
+	x, y             int
+	index, dimension int
+}
+
+// hash returns a hash of the dimension (and the pixel when perPixel is true) used to scramble the pattern. salt
+// distinguishes the different hashes needed for the same dimension
+func (c sampleCoordinates) hash(perPixel bool, salt uint64) uint64 {
+	h := c.seed
+	if perPixel {
+		h = c.pixel
+	}
+	h = mix64(h + uint64(c.dimension) + splitmix64Increment)
+	return mix64(h + salt + splitmix64Increment)
+}
+
+// random returns a (hashed) random number specific to the sample and its dimension
+func (c sampleCoordinates) random(salt uint64) float64 {
+	h := mix64(c.hash(true, salt) + uint64(c.index) + splitmix64Increment)
+	return float64(h>>11) / (1 << 53)
+}
+
+// patternSampler keeps track of the coordinates of the current sample and delegates to the pattern
+type patternSampler struct {
+	pattern     samplePattern
+	seed        int64
+	coordinates sampleCoordinates
+}
+
+func (s *patternSampler) StartPixel(x, y, pass int) {
+	seed := mix64(uint64(s.seed) + splitmix64Increment)
+	pixel := mix64(seed + uint64(x) + splitmix64Increment)
+	pixel = mix64(pixel + uint64(y) + splitmix64Increment)
+	s.coordinates = sampleCoordinates{seed: seed, pixel: pixel, x: x, y: y}
+}
+
+func (s *patternSampler) StartSample(index int) {
+	s.coordinates.index = index
+	s.coordinates.dimension = 0
+}
+
+func (s *patternSampler) Float64() float64 {
+	return s.Get1D()
+}
+
+func (s *patternSampler) Get1D() float64 {
+	v := s.pattern.sample1D(s.coordinates)
+	s.coordinates.dimension++
+	return v
+}
+
+func (s *patternSampler) Get2D() (float64, float64) {
+	u, v := s.pattern.sample2D(s.coordinates)
+	s.coordinates.dimension += 2
+	return u, v
+}
+
+/***********************
+ * stratified
+ ************************/
+// stratifiedPattern jitters each sample in its own stratum. The strata are assigned to the samples with a
+// permutation which is different for each pixel and dimension so that the dimensions are not correlated
+type stratifiedPattern struct {
+	samplesPerPixel int
+}
+
+func (p stratifiedPattern) sample1D(c sampleCoordinates) float64 {
+	n := p.samplesPerPixel
+	stratum := permute(uint32(c.index%n), uint32(n), uint32(c.hash(true, 0)))
+	return (float64(stratum) + c.random(1)) / float64(n)
+}
+
+// sample2D uses a grid of nx x ny strata (nx * ny >= samplesPerPixel, as square as possible) and only the first
+// samplesPerPixel strata of the permutation are used when the number of samples is not a product of 2 close numbers
+func (p stratifiedPattern) sample2D(c sampleCoordinates) (float64, float64) {
+	n := p.samplesPerPixel
+	nx := int(math.Ceil(math.Sqrt(float64(n))))
+	ny := (n + nx - 1) / nx
+	stratum := int(permute(uint32(c.index%n), uint32(nx*ny), uint32(c.hash(true, 0))))
+	return (float64(stratum%nx) + c.random(1)) / float64(nx), (float64(stratum/nx) + c.random(2)) / float64(ny)
+}
+
+// permute returns the element i of a random permutation of [0,l) (defined by p) without computing the permutation
+// (Kensler, "Correlated Multi-Jittered Sampling")
+func permute(i, l, p uint32) uint32 {
+	w := l - 1
+	w |= w >> 1
+	w |= w >> 2
+	w |= w >> 4
+	w |= w >> 8
+	w |= w >> 16
+	for {
+		i ^= p
+		i *= 0xe170893d
+		i ^= p >> 16
+		i ^= (i & w) >> 4
+		i ^= p >> 8
+		i *= 0x0929eb3f
+		i ^= p >> 23
+		i ^= (i & w) >> 1
+		i *= 1 | p>>27
+		i *= 0x6935fa69
+		i ^= (i & w) >> 11
+		i *= 0x74dcb303
+		i ^= (i & w) >> 2
+		i *= 0x9e501cc3
+		i ^= (i & w) >> 2
+		i *= 0xc860a3df
+		i &= w
+		i ^= i >> 5
+		// cycle walking: the permutation is over the next power of 2 so the values >= l are skipped
+		if i < l {
+			break
+		}
+	}
+	return (i + p) % l
+}
+
+/***********************
+ * Halton
+ ************************/
+// haltonPattern uses the radical inverse in base primes[dimension] of the index of the sample whose digits are Owen
+// scrambled (with a seed specific to the pixel and dimension) which decorrelates the pixels and the dimensions. The
+// dimensions beyond the primes of the table (which are rarely reached) are random.
+type haltonPattern struct{}
+
+// haltonPrimes are the bases of the dimensions of the Halton sequence
+var haltonPrimes = firstPrimes(128)
+
+func (p haltonPattern) sample1D(c sampleCoordinates) float64 {
+	if c.dimension >= len(haltonPrimes) {
+		return c.random(0)
+	}
+	return owenScrambledRadicalInverse(haltonPrimes[c.dimension], c.index, c.hash(true, 0))
+}
+
+func (p haltonPattern) sample2D(c sampleCoordinates) (float64, float64) {
+	u := p.sample1D(c)
+	c.dimension++
+	return u, p.sample1D(c)
+}
+
+// owenScrambledRadicalInverse mirrors the digits (in base) of index around the decimal point (ex: 6 = 110b =>
+// 0.011b = 0.375) and permutes each digit with a permutation which depends on the seed and the digits before it
+// (nested uniform scrambling). Since the (infinite) trailing zeros are permuted too, the digits are computed up to
+// the precision of a float64.
+func owenScrambledRadicalInverse(base, index int, seed uint64) float64 {
+	digits := int(math.Ceil(53 / math.Log2(float64(base))))
+	inverse, factor := 0.0, 1.0/float64(base)
+	f := factor
+	for d := 0; d < digits; d++ {
+		digit := index % base
+		index /= base
+		inverse += float64(permute(uint32(digit), uint32(base), uint32(seed))) * f
+		f *= factor
+		seed = mix64(seed + uint64(digit) + splitmix64Increment)
+	}
+	return math.Min(inverse, math.Nextafter(1, 0))
+}
+
+// shift adds the offset to v modulo 1 (the result is in [0,1))
+func shift(v, offset float64) float64 {
+	v += offset
+	if v >= 1 {
+		v--
+	}
+	// rounding may produce exactly 1
+	if v >= 1 {
+		return math.Nextafter(1, 0)
+	}
+	return v
+}
+
+// firstPrimes returns the first n prime numbers
+func firstPrimes(n int) []int {
+	primes := make([]int, 0, n)
+	for candidate := 2; len(primes) < n; candidate++ {
+		prime := true
+		for _, p := range primes {
+			if p*p > candidate {
+				break
+			}
+			if candidate%p == 0 {
+				prime = false
+				break
+			}
+		}
+		if prime {
+			primes = append(primes, candidate)
+		}
+	}
+	return primes
+}
+
+/***********************
+ * Sobol
+ ************************/
+// sobolPattern uses the first 2 dimensions of the Sobol sequence (a (0,2)-sequence: every power of 2 prefix is
+// perfectly stratified) for each pair of dimensions (padding). The index and the values are Owen scrambled (nested
+// uniform scrambling, which keeps the stratification) with a different seed for each pixel and pair of dimensions
+// so that the pairs are not correlated (Burley, "Practical Hash-based Owen Scrambling").
+type sobolPattern struct{}
+
+func (p sobolPattern) sample1D(c sampleCoordinates) float64 {
+	return scrambledSobol1D(c.index, c.hash(true, 0))
+}
+
+func (p sobolPattern) sample2D(c sampleCoordinates) (float64, float64) {
+	return scrambledSobol2D(c.index, c.hash(true, 0))
+}
+
+// scrambledSobol1D returns the (scrambled) van der Corput sequence which is the first dimension of Sobol
+func scrambledSobol1D(index int, seed uint64) float64 {
+	i := nestedUniformScramble(uint32(index), uint32(seed))
+	return uint32ToUnit(nestedUniformScramble(bits.Reverse32(i), uint32(seed>>32)))
+}
+
+// scrambledSobol2D returns the first 2 dimensions of the Sobol sequence (scrambled)
+func scrambledSobol2D(index int, seed uint64) (float64, float64) {
+	i := nestedUniformScramble(uint32(index), uint32(seed))
+	h := mix64(seed)
+	u := nestedUniformScramble(bits.Reverse32(i), uint32(h))
+	v := nestedUniformScramble(sobol2(i), uint32(h>>32))
+	return uint32ToUnit(u), uint32ToUnit(v)
+}
+
+// sobol2 computes the second dimension of the Sobol sequence (the bits of the result are the fractional digits)
+func sobol2(index uint32) uint32 {
+	v, r := uint32(1<<31), uint32(0)
+	for ; index != 0; index >>= 1 {
+		if index&1 != 0 {
+			r ^= v
+		}
+		v ^= v >> 1
+	}
+	return r
+}
+
+// nestedUniformScramble is an Owen scrambling of the fractional digits x: flipping a digit only depends on the
+// digits before it (laineKarrasPermutation operates on reversed bits which is why x is reversed twice)
+func nestedUniformScramble(x, seed uint32) uint32 {
+	return bits.Reverse32(laineKarrasPermutation(bits.Reverse32(x), seed))
+}
+
+// laineKarrasPermutation is a hash in which each bit only depends on the lower bits (Laine and Karras, improved
+// constants by Burley)
+func laineKarrasPermutation(x, seed uint32) uint32 {
+	x += seed
+	x ^= x * 0x6c50b47c
+	x ^= x * 0xb82f1e52
+	x ^= x * 0xc7afe638
+	x ^= x * 0x8d22f6e6
+	return x
+}
+
+// uint32ToUnit converts the fractional digits into a number in [0,1)
+func uint32ToUnit(x uint32) float64 {
+	return float64(x) / (1 << 32)
+}
+
+/***********************
+ * Blue noise
+ ************************/
+// blueNoisePattern uses Owen scrambled Sobol points which are the same for every pixel (only the seed and the
+// dimension define the scrambling) shifted (modulo 1) by the value of a blue noise mask at the pixel. Since
+// neighboring pixels have very different values in the mask, their errors tend to cancel out visually. The mask is
+// tiled over the image and offset differently for each dimension.
+type blueNoisePattern struct {
+	mask []float64
+}
+
+func (p blueNoisePattern) sample1D(c sampleCoordinates) float64 {
+	return shift(scrambledSobol1D(c.index, c.hash(false, 0)), p.offset(c, 0))
+}
+
+func (p blueNoisePattern) sample2D(c sampleCoordinates) (float64, float64) {
+	u, v := scrambledSobol2D(c.index, c.hash(false, 0))
+	return shift(u, p.offset(c, 0)), shift(v, p.offset(c, 1))
+}
+
+// offset returns the value of the mask for the pixel (the mask is offset by a hash of the dimension and axis)
+func (p blueNoisePattern) offset(c sampleCoordinates, axis uint64) float64 {
+	h := c.hash(false, 4+axis)
+	x := (c.x + int(h%blueNoiseSize)) % blueNoiseSize
+	y := (c.y + int((h>>32)%blueNoiseSize)) % blueNoiseSize
+	if x < 0 {
+		x += blueNoiseSize
+	}
+	if y < 0 {
+		y += blueNoiseSize
+	}
+	return p.mask[y*blueNoiseSize+x]
+}
+
+// blueNoiseSize is the size (in pixels) of the (square) blue noise mask
+const blueNoiseSize = 64
+
+var blueNoiseOnce sync.Once
+var blueNoise []float64
+
+// blueNoiseMask returns the blue noise mask (computed the first time it is needed)
+func blueNoiseMask() []float64 {
+	blueNoiseOnce.Do(func() {
+		blueNoise = voidAndCluster(blueNoiseSize, 1.5)
+	})
+	return blueNoise
+}
+
+// voidAndCluster generates a size x size blue noise mask (tileable) with the void and cluster method (Ulichney):
+// the pixels are ranked by repeatedly inserting a pixel in the largest void (or removing the pixel in the tightest
+// cluster) of a binary pattern, the voids and clusters being measured with a gaussian of deviation sigma. Each
+// value of the mask is (rank + 0.5) / (size * size): the values are uniformly distributed in [0,1).
+func voidAndCluster(size int, sigma float64) []float64 {
+	n := size * size
+
+	// energy contributed by a pixel to the pixel dx/dy away (the mask wraps around)
+	lut := make([]float64, n)
+	for dy := 0; dy < size; dy++ {
+		for dx := 0; dx < size; dx++ {
+			x, y := float64(minInt(dx, size-dx)), float64(minInt(dy, size-dy))
+			lut[dy*size+dx] = math.Exp(-(x*x + y*y) / (2 * sigma * sigma))
+		}
+	}
+
+	type binaryPattern struct {
+		on     []bool
+		energy []float64
+	}
+	toggle := func(p *binaryPattern, k int) {
+		p.on[k] = !p.on[k]
+		sign := 1.0
+		if !p.on[k] {
+			sign = -1
+		}
+		kx, ky := k%size, k/size
+		for y := 0; y < size; y++ {
+			row := ((y - ky + size) % size) * size
+			for x := 0; x < size; x++ {
+				p.energy[y*size+x] += sign * lut[row+(x-kx+size)%size]
+			}
+		}
+	}
+	// tightestCluster returns the pixel on with the highest energy
+	tightestCluster := func(p *binaryPattern) int {
+		best := -1
+		for k := 0; k < n; k++ {
+			if p.on[k] && (best < 0 || p.energy[k] > p.energy[best]) {
+				best = k
+			}
+		}
+		return best
+	}
+	// largestVoid returns the pixel off with the lowest energy
+	largestVoid := func(p *binaryPattern) int {
+		best := -1
+		for k := 0; k < n; k++ {
+			if !p.on[k] && (best < 0 || p.energy[k] < p.energy[best]) {
+				best = k
+			}
+		}
+		return best
+	}
+	clone := func(p *binaryPattern) *binaryPattern {
+		return &binaryPattern{on: append([]bool(nil), p.on...), energy: append([]float64(nil), p.energy...)}
+	}
+
+	// initial pattern: 10% of random pixels evenly spread by moving the pixel in the tightest cluster to the largest
+	// void until it does not move anymore
+	initial := &binaryPattern{on: make([]bool, n), energy: make([]float64, n)}
+	rnd := newPixelRnd(0, size, size, 0)
+	ones := n / 10
+	for count := 0; count < ones; {
+		if k := int(rnd.Uint64() % uint64(n)); !initial.on[k] {
+			toggle(initial, k)
+			count++
+		}
+	}
+	for {
+		cluster := tightestCluster(initial)
+		toggle(initial, cluster)
+		void := largestVoid(initial)
+		toggle(initial, void)
+		if void == cluster {
+			break
+		}
+	}
+
+	ranks := make([]int, n)
+
+	// the pixels of the initial pattern are ranked by removing them (tightest cluster first)
+	p := clone(initial)
+	for rank := ones - 1; rank >= 0; rank-- {
+		k := tightestCluster(p)
+		toggle(p, k)
+		ranks[k] = rank
+	}
+
+	// the other ones are ranked by filling the largest void
+	p = initial
+	for rank := ones; rank < n; rank++ {
+		k := largestVoid(p)
+		toggle(p, k)
+		ranks[k] = rank
+	}
+
+	mask := make([]float64, n)
+	for k, rank := range ranks {
+		mask[k] = (float64(rank) + 0.5) / float64(n)
+	}
+	return mask
+}
@@ -0,0 +1,238 @@
+package tracer
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestSamplerType_Set(t *testing.T) {
+	for i, name := range samplerTypeNames {
+		var st SamplerType
+		if err := st.Set(name); err != nil || st != SamplerType(i) || st.String() != name {
+			t.Errorf("%v expected got %v (%v) instead", name, st, err)
+		}
+	}
+
+	var st SamplerType
+	if err := st.Set("random"); err == nil {
+		t.Errorf("error expected for unknown sampler")
+	}
+}
+
+func TestSampler_Deterministic(t *testing.T) {
+	for i := range samplerTypeNames {
+		st := SamplerType(i)
+
+		values := func(seed int64, x, y int) []float64 {
+			s := NewSampler(st, seed, 16)
+			s.StartPixel(x, y, 0)
+			var res []float64
+			for index := 0; index < 16; index++ {
+				s.StartSample(index)
+				u, v := s.Get2D()
+				res = append(res, u, v, s.Get1D(), s.Float64())
+			}
+			return res
+		}
+
+		expected := values(2017, 3, 4)
+		for _, v := range expected {
+			if v < 0 || v >= 1 {
+				t.Fatalf("%v: %v out of range", st, v)
+			}
+		}
+
+		same := func(a, b []float64) bool {
+			for k := range a {
+				if a[k] != b[k] {
+					return false
+				}
+			}
+			return true
+		}
+
+		if !same(expected, values(2017, 3, 4)) {
+			t.Errorf("%v: same values expected", st)
+		}
+		if same(expected, values(2018, 3, 4)) {
+			t.Errorf("%v: a different seed should produce different values", st)
+		}
+		if same(expected, values(2017, 4, 3)) {
+			t.Errorf("%v: a different pixel should produce different values", st)
+		}
+	}
+}
+
+func TestSampler_Stratification(t *testing.T) {
+	const n = 16
+
+	var tests = []struct {
+		sampler SamplerType
+		check2D bool
+	}{
+		{SamplerStratified, true},
+		{SamplerHalton, false},
+		{SamplerSobol, true},
+	}
+
+	for idx, test := range tests {
+		s := NewSampler(test.sampler, 2017, n)
+		for pixel := 0; pixel < 4; pixel++ {
+			s.StartPixel(pixel, 2*pixel, 0)
+
+			// for each dimension (a single one then a pair of dimensions), every stratum gets exactly one sample
+			strata1D := make([]int, n)
+			strata2D := make([]int, n)
+			for index := 0; index < n; index++ {
+				s.StartSample(index)
+				strata1D[int(s.Get1D()*n)]++
+				u, v := s.Get2D()
+				strata2D[int(v*4)*4+int(u*4)]++
+			}
+
+			for k := 0; k < n; k++ {
+				if strata1D[k] != 1 {
+					t.Errorf("1D stratum %v has %v samples [test %v]", k, strata1D[k], idx)
+				}
+				if test.check2D && strata2D[k] != 1 {
+					t.Errorf("2D stratum %v has %v samples [test %v]", k, strata2D[k], idx)
+				}
+			}
+		}
+	}
+}
+
+func TestSampler_Convergence(t *testing.T) {
+	// integrates a smooth function over many pixels: the low discrepancy samplers must be more accurate than
+	// independent samples
+	f := func(u, v float64) float64 {
+		return u*v + math.Sin(3*u)*math.Cos(2*v)
+	}
+	expected := 0.25 + (1-math.Cos(3))/3*math.Sin(2)/2
+
+	rmse := func(st SamplerType) float64 {
+		const samples = 32
+		s := NewSampler(st, 2017, samples)
+		sum := 0.0
+		for pixel := 0; pixel < 100; pixel++ {
+			s.StartPixel(pixel%10, pixel/10, 0)
+			estimate := 0.0
+			for index := 0; index < samples; index++ {
+				s.StartSample(index)
+				// skips the first dimensions like the pixel position would
+				s.Get2D()
+				estimate += f(s.Get2D())
+			}
+			e := estimate/samples - expected
+			sum += e * e
+		}
+		return math.Sqrt(sum / 100)
+	}
+
+	independent := rmse(SamplerIndependent)
+	for _, st := range []SamplerType{SamplerStratified, SamplerHalton, SamplerSobol, SamplerBlueNoise} {
+		if e := rmse(st); e >= independent/2 {
+			t.Errorf("%v: error %v should be much less than %v", st, e, independent)
+		}
+	}
+}
+
+func TestPermute(t *testing.T) {
+	for _, l := range []uint32{1, 5, 16, 33} {
+		seen := make([]bool, l)
+		for i := uint32(0); i < l; i++ {
+			p := permute(i, l, 0x12345678)
+			if p >= l || seen[p] {
+				t.Fatalf("%v is not a permutation of [0,%v)", p, l)
+			}
+			seen[p] = true
+		}
+	}
+}
+
+func TestBlueNoiseMask(t *testing.T) {
+	mask := blueNoiseMask()
+
+	// every value appears once
+	seen := make([]bool, len(mask))
+	for _, v := range mask {
+		k := int(v * float64(len(mask)))
+		if seen[k] {
+			t.Fatalf("%v appears twice", v)
+		}
+		seen[k] = true
+	}
+
+	// neighboring pixels have very different values (1/3 on average for white noise)
+	diff := 0.0
+	for y := 0; y < blueNoiseSize; y++ {
+		for x := 0; x < blueNoiseSize; x++ {
+			v := mask[y*blueNoiseSize+x]
+			diff += math.Abs(v - mask[y*blueNoiseSize+(x+1)%blueNoiseSize])
+			diff += math.Abs(v - mask[((y+1)%blueNoiseSize)*blueNoiseSize+x])
+		}
+	}
+	if diff /= float64(2 * len(mask)); diff < 0.4 {
+		t.Errorf("average difference between neighbors %v should be more than 0.4", diff)
+	}
+}
+
+func TestRandomInUnitDisk_Sampler(t *testing.T) {
+	s := NewSampler(SamplerSobol, 2017, 64)
+	s.StartPixel(0, 0, 0)
+	for index := 0; index < 64; index++ {
+		s.StartSample(index)
+		if p := RandomInUnitDisk(s); Dot(p, p) > 1 || p.Z != 0 {
+			t.Errorf("%v is not in the unit disk", p)
+		}
+		if p := RandomInUnitSphere(s); Dot(p, p) > 1 {
+			t.Errorf("%v is not in the unit sphere", p)
+		}
+	}
+
+	// the corners of the square are on the circle and the center in the center
+	var tests = []struct {
+		u, v float64
+		r    float64
+	}{
+		{0.5, 0.5, 0},
+		{0, 0, 1},
+		{1, 0.5, 1},
+		{0.75, 0.5, 0.5},
+		{0.5, 0.25, 0.5},
+	}
+	for idx, test := range tests {
+		if p := concentricDisk(test.u, test.v); math.Abs(p.Length()-test.r) > 1e-9 {
+			t.Errorf("%v expected got %v instead [test %v]", test.r, p.Length(), idx)
+		}
+	}
+}
+
+func TestRenderContext_Sampler(t *testing.T) {
+	for i := range samplerTypeNames {
+		st := SamplerType(i)
+
+		render := func(parallelCount int, order TileOrder) *HDRImage {
+			scene := testScene(20, 10, 1, 3)
+			scene.Camera = NewCamera(Point3{Z: 3}, Point3{}, Vec3{Y: 1}, 30, 2, 0.1, 3)
+			scene.Sampler = st
+			scene.TileSize = 4
+			scene.TileOrder = order
+			fb, done := scene.RenderContext(context.Background(), parallelCount, nil)
+			if err := <-done; err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			return fb.Accumulation().Image()
+		}
+
+		expected := render(1, TileScan)
+		actual := render(5, TileHilbert)
+		for k := range expected.Pixels {
+			if expected.Pixels[k] != actual.Pixels[k] {
+				t.Errorf("%v: pixel %v %v expected got %v instead", st, k, expected.Pixels[k], actual.Pixels[k])
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package tracer
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Pixels represents the array of pixels (in packed RGB value, row by row from the top) to display and/or save
+// (see FrameBuffer.Snapshot)
+type Pixels []uint32
+
+// Scene represents the scene to Render.
+//   World is what the Camera sees (ideally a bounding volume hierarchy, see BuildBVH) and Background is the color
+//                of the rays which do not hit anything in the World
+//   RaysPerPixel is an array because the Render algorithm is split in multiple passes so that a result can be
+//                available as soon as possible
+//   Seed is the seed of the random numbers used while rendering (the same seed always produces the same image
+//                whatever the number of goroutines)
+//   ToneMapper/Exposure define how the linear colors are converted for display (ClampToneMapper when nil) after
+//                being scaled by the exposure (in stops, see ExposureScale)
+//   OutputTransform encodes the tone mapped colors into the values of the pixels (SRGBTransform when nil)
+//   Filter is the pixel reconstruction filter (BoxFilter of radius 0.5 when nil, see Filter). With a filter wider
+//                than a pixel, the samples are accumulated across tiles so the image also depends on TileSize
+//   TileSize/TileOrder define how the image is split in tiles (DefaultTileSize when 0) and in which order they are
+//                rendered (see Tiles)
+//   Sampler defines how the samples (position in the pixel, on the lens, scattered directions...) are distributed
+//                (SamplerIndependent by default, see Sampler)
+type Scene struct {
+	Width, Height   int
+	RaysPerPixel    []int
+	Camera          Camera
+	World           Hitable
+	Background      Background
+	Seed            int64
+	ToneMapper      ToneMapper
+	Exposure        float64
+	OutputTransform OutputTransform
+	Filter          Filter
+	TileSize        int
+	TileOrder       TileOrder
+	Sampler         SamplerType
+}
+
+// pixel is an internal type which represents the pixel to be processed
+//	x,y are the coordinates (for the camera: y = 0 is the bottom row)
+//	k is the index in the FrameBuffer and AccumulationBuffer (row by row from the top)
+type pixel struct {
+	x, y, k int
+}
+
+// render works on a single pixel, casting raysPerPixel through it (firstSample is the index of the first one) and
+// splatting the colors in the tile buffer (not normalized so that it can be accumulated without rounding errors,
+// see AccumulationBuffer)
+func (scene *Scene) render(sampler Sampler, pixel pixel, firstSample, raysPerPixel int, filter Filter, tb *tileBuffer) {
+	// coordinates of the pixel in the image (y = 0 is the top row)
+	ix, iy := float64(pixel.x), float64(scene.Height-1-pixel.y)
+
+	for s := 0; s < raysPerPixel; s++ {
+		sampler.StartSample(firstSample + s)
+		du, dv := sampler.Get2D()
+		u := (float64(pixel.x) + du) / float64(scene.Width)
+		v := (float64(pixel.y) + dv) / float64(scene.Height)
+		r := scene.Camera.Ray(sampler, u, v)
+		tb.splat(filter, ix+du, iy+1-dv, color(r, scene.World, scene.Background, 0, true))
+	}
+}
+
+// filter returns the reconstruction filter of the scene (BoxFilter of radius 0.5 when not defined)
+func (scene *Scene) filter() Filter {
+	if scene.Filter == nil {
+		return BoxFilter{R: 0.5}
+	}
+	return scene.Filter
+}
+
+// toneMap applies the exposure and the tone mapper of the scene to the (average) color of a pixel
+func (scene *Scene) toneMap(c Color) Color {
+	c = c.Scale(ExposureScale(scene.Exposure))
+	if scene.ToneMapper == nil {
+		return ClampToneMapper{}.ToneMap(c)
+	}
+	return scene.ToneMapper.ToneMap(c)
+}
+
+// displayValue converts the (average) color of a pixel into the value displayed (tone mapped and encoded with the
+// output transform)
+func (scene *Scene) displayValue(c Color) uint32 {
+	c = scene.toneMap(c)
+	if scene.OutputTransform == nil {
+		c = SRGBTransform{}.Encode(c)
+	} else {
+		c = scene.OutputTransform.Encode(c)
+	}
+	return c.PixelValue()
+}
+
+// Render is the main method of a scene. It is non blocking and returns right away with the frame buffer whose
+// pixels will be computed asynchronously and a channel to indicate when the processing is complete. The frame buffer
+// can safely be read (see FrameBuffer.Snapshot) while the scene is being rendered.
+// The image (width x height) will be split in tiles (see Tiles) each one processed in a separate goroutine
+// (parallelCount of them). The image will be progressively rendered using the passes defined in raysPerPixel.
+// No progress is reported (see RenderContext to follow the progress or cancel the rendering)
+func (scene *Scene) Render(parallelCount int) (*FrameBuffer, chan struct{}) {
+	fb, done := scene.RenderContext(context.Background(), parallelCount, nil)
+
+	completed := make(chan struct{})
+	go func() {
+		<-done
+		completed <- struct{}{}
+	}()
+
+	return fb, completed
+}
+
+// RenderContext is the same as Render but the rendering stops (promptly, every goroutine checks the context
+// before each pixel) when ctx is done and the progress is reported by calling progress (optional) with typed
+// events (see ProgressEvent). The channel receives nil once the image is completely rendered or the error of the
+// context if the rendering was cancelled (in which case the frame buffer is only partially rendered)
+func (scene *Scene) RenderContext(ctx context.Context, parallelCount int, progress ProgressFunc) (*FrameBuffer, chan error) {
+	accumulation := NewAccumulationBuffer(scene.Width, scene.Height)
+	fb := NewFrameBuffer(scene.Width, scene.Height)
+	fb.accumulation = accumulation
+	done := make(chan error, 1)
+
+	go func() {
+		// a sample contributes to the pixels of the neighboring tiles up to this margin
+		filter := scene.filter()
+		margin := maxInt(int(math.Ceil(filter.Radius()-0.5)), 0)
+
+		// split in tiles (in the order they get rendered)
+		type tileToProcess struct {
+			index  int
+			tile   Tile
+			pixels []pixel
+		}
+		var tiles []tileToProcess
+		for index, tile := range Tiles(scene.Width, scene.Height, scene.TileSize, scene.TileOrder) {
+			ps := make([]pixel, 0, (tile.X1-tile.X0)*(tile.Y1-tile.Y0))
+			for y := tile.Y0; y < tile.Y1; y++ {
+				for x := tile.X0; x < tile.X1; x++ {
+					ps = append(ps, pixel{x: x, y: scene.Height - 1 - y, k: y*scene.Width + x})
+				}
+			}
+			tiles = append(tiles, tileToProcess{index: index, tile: tile, pixels: ps})
+		}
+
+		// the tiles are accumulated at the end of each pass from top to bottom/left to right (whatever the order in
+		// which they have been rendered) so that the result is deterministic
+		mergeOrder := make([]int, len(tiles))
+		for i := range mergeOrder {
+			mergeOrder[i] = i
+		}
+		sort.Slice(mergeOrder, func(a, b int) bool {
+			ta, tb := tiles[mergeOrder[a]].tile, tiles[mergeOrder[b]].tile
+			return ta.Y0 < tb.Y0 || (ta.Y0 == tb.Y0 && ta.X0 < tb.X0)
+		})
+
+		// computes the stats (elapsed time, estimated remaining time...) and emits the events
+		tracker := newProgressTracker(scene, progress)
+
+		// the samples of a pixel are numbered across the passes (see Sampler)
+		totalRaysPerPixel, firstSample := 0, 0
+		for _, rpp := range scene.RaysPerPixel {
+			totalRaysPerPixel += rpp
+		}
+
+		// loop for each phase
+		for pass, rpp := range scene.RaysPerPixel {
+			if err := ctx.Err(); err != nil {
+				tracker.emit(RenderCancelled, pass)
+				done <- err
+				return
+			}
+
+			tracker.emit(PassStarted, pass)
+
+			// creates a channel which will be used to dispatch the tile to process to each go routine
+			tilesToProcess := make(chan tileToProcess)
+
+			// asynchronously dispatch the tiles to process (until cancelled)
+			go func() {
+				defer close(tilesToProcess)
+				for _, t := range tiles {
+					select {
+					case tilesToProcess <- t:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+
+			// the samples of each tile for this pass (each entry is written by only one goroutine and read after
+			// all of them are done)
+			tileBuffers := make([]*tileBuffer, len(tiles))
+
+			// create a wait group to wait until all goroutine completes
+			wg := sync.WaitGroup{}
+
+			// create parallelCount goroutines
+			for c := 0; c < parallelCount; c++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					// due to high contention on global rand, each goroutine uses its own sampler thus avoiding
+					// massive slowdown. It is restarted for each pixel (see Sampler) so that the result does not
+					// depend on which goroutine renders the pixel
+					sampler := NewSampler(scene.Sampler, scene.Seed, totalRaysPerPixel)
+
+					var ks []int
+					var means []Color
+
+					// process a bunch of pixels (in this case a tile)
+					for t := range tilesToProcess {
+						ks = ks[:0]
+						for _, p := range t.pixels {
+							ks = append(ks, p.k)
+						}
+						if cap(means) < len(ks) {
+							means = make([]Color, len(ks))
+						}
+						means = means[:len(ks)]
+
+						// redisplay the tile without output transform => make it darker to be more visible
+						if pass > 0 {
+							accumulation.means(ks, nil, means)
+							for i, k := range ks {
+								fb.store(k, scene.toneMap(means[i]).PixelValue())
+							}
+						}
+
+						// render every pixel in the tile (only the pixels rendered are kept if cancelled)
+						tb := newTileBuffer(t.tile, margin, scene.Width, scene.Height)
+						for _, p := range t.pixels {
+							if isDone(ctx) {
+								break
+							}
+							sampler.StartPixel(p.x, p.y, pass)
+							scene.render(sampler, p, firstSample, rpp, filter, tb)
+						}
+						tileBuffers[t.index] = tb
+
+						// display a preview of the tile (the samples of the neighboring tiles are missing until the
+						// end of the pass)
+						accumulation.means(ks, tb, means)
+						for i, k := range ks {
+							fb.store(k, scene.displayValue(means[i]))
+						}
+
+						fb.Publish()
+					}
+				}()
+			}
+
+			// wait for the pass to be completed (or cancelled)
+			wg.Wait()
+
+			// accumulate the pass (deterministic order) and display the final result of the pass
+			for _, index := range mergeOrder {
+				if tb := tileBuffers[index]; tb != nil {
+					accumulation.addTile(tb)
+				}
+			}
+			if margin > 0 || ctx.Err() != nil {
+				img := accumulation.Image()
+				for k, c := range img.Pixels {
+					fb.store(k, scene.displayValue(c))
+				}
+				fb.Publish()
+			}
+
+			if err := ctx.Err(); err != nil {
+				tracker.emit(RenderCancelled, pass)
+				done <- err
+				return
+			}
+
+			tracker.emit(PassFinished, pass)
+			firstSample += rpp
+		}
+
+		// signal completion
+		tracker.emit(RenderCompleted, len(scene.RaysPerPixel)-1)
+		done <- nil
+	}()
+
+	return fb, done
+}
+
+// isDone checks (without blocking) whether the context is done. Unlike ctx.Err(), it does not lock so it is cheap
+// enough to be called for every pixel
+func isDone(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// color computes the color of the ray by checking which hitable gets hit and scattering
+// more rays (recursive) depending on material. The light emitted by the material (if any) is added to the
+// scattered light. When nothing is hit, the color comes from the background.
+// When the background can be sampled (ex: environment map), the light it contributes to diffuse materials is
+// computed explicitly by sampling it (see sampleBackground) in which case countBackground is false for the scattered
+// ray so that this light is not counted twice (the background is still counted after a specular bounce)
+func color(r *Ray, world Hitable, background Background, depth int, countBackground bool) Color {
+
+	if hit, hr := world.Hit(r, 0.001, math.MaxFloat64); hit {
+		if depth >= 50 {
+			return Black
+		}
+
+		emitted := hr.Material.Emitted(hr)
+
+		if wasScattered, attenuation, scattered := hr.Material.Scatter(r, hr); wasScattered {
+			if sb, ok := background.(sampledBackground); ok {
+				if dm, ok := hr.Material.(diffuseMaterial); ok {
+					direct := sampleBackground(r.Rnd, sb, world, hr, dm.diffuseAlbedo(hr))
+					return emitted.Add(direct).Add(attenuation.Mult(color(scattered, world, background, depth+1, false)))
+				}
+			}
+			return emitted.Add(attenuation.Mult(color(scattered, world, background, depth+1, true)))
+		} else {
+			return emitted
+		}
+	}
+
+	if !countBackground {
+		return Black
+	}
+
+	return background.Color(r)
+}
+
+// sampleBackground computes the light coming directly from the background to a diffuse (lambertian) surface by
+// choosing a direction according to the background distribution (and checking that nothing is in the way)
+func sampleBackground(rnd Rnd, background sampledBackground, world Hitable, hr *HitRecord, albedo Color) Color {
+	direction, radiance, pdf := background.sample(rnd)
+	if pdf <= 0 {
+		return Black
+	}
+
+	cosine := Dot(direction, hr.Normal.Unit())
+	if cosine <= 0 {
+		return Black
+	}
+
+	if hit, _ := world.Hit(&Ray{hr.P, direction, rnd}, 0.001, math.MaxFloat64); hit {
+		return Black
+	}
+
+	// lambertian brdf is albedo / pi
+	return albedo.Mult(radiance).Scale(cosine / (math.Pi * pdf))
+}
@@ -0,0 +1,201 @@
+package tracer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func testScene(width, height int, raysPerPixel ...int) *Scene {
+	camera := NewCamera(Point3{Z: 3}, Point3{}, Vec3{Y: 1}, 30, float64(width)/float64(height), 0, 3)
+	world := HitableList{
+		Sphere{Center: Point3{}, Radius: 0.5, Material: Lambertian{Albedo: Color{R: 0.8, G: 0.3, B: 0.3}}},
+		Sphere{Center: Point3{Y: -100.5}, Radius: 100, Material: Metal{Albedo: White, Fuzz: 0.1}},
+	}
+	return &Scene{Width: width, Height: height, RaysPerPixel: raysPerPixel, Camera: camera, World: world, Background: SkyBackground}
+}
+
+func TestRenderContext_Events(t *testing.T) {
+	scene := testScene(8, 4, 1, 2, 3)
+
+	var events []ProgressEvent
+	fb, done := scene.RenderContext(context.Background(), 2, func(event ProgressEvent) {
+		events = append(events, event)
+	})
+
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var expected = []struct {
+		kind         ProgressKind
+		pass         int
+		raysPerPixel int
+		accumulated  int
+	}{
+		{PassStarted, 0, 1, 0},
+		{PassFinished, 0, 1, 1},
+		{PassStarted, 1, 2, 1},
+		{PassFinished, 1, 2, 3},
+		{PassStarted, 2, 3, 3},
+		{PassFinished, 2, 3, 6},
+		{RenderCompleted, 2, 3, 6},
+	}
+
+	if len(events) != len(expected) {
+		t.Fatalf("%v events expected got %v instead (%v)", len(expected), len(events), events)
+	}
+
+	for idx, e := range expected {
+		event := events[idx]
+		if event.Kind != e.kind || event.Pass != e.pass || event.RaysPerPixel != e.raysPerPixel || event.AccumulatedRaysPerPixel != e.accumulated {
+			t.Errorf("%v/%v/%v/%v expected got %v/%v/%v/%v instead [test %v]", e.kind, e.pass, e.raysPerPixel, e.accumulated, event.Kind, event.Pass, event.RaysPerPixel, event.AccumulatedRaysPerPixel, idx)
+		}
+		if event.Passes != 3 || event.TotalRaysPerPixel != 6 {
+			t.Errorf("3 passes/6 rays per pixel expected got %v/%v instead [test %v]", event.Passes, event.TotalRaysPerPixel, idx)
+		}
+	}
+
+	if last := events[len(events)-1]; last.ETA != 0 || last.RaysPerSecond <= 0 {
+		t.Errorf("no ETA and positive rays per second expected got %v/%v instead", last.ETA, last.RaysPerSecond)
+	}
+
+	pixels, _ := fb.Snapshot(nil)
+	for k, p := range pixels {
+		if p == 0 {
+			t.Errorf("pixel %v not rendered", k)
+		}
+	}
+}
+
+func TestRenderContext_Cancel(t *testing.T) {
+	// way too many rays per pixel to complete during the test
+	scene := testScene(16, 8, 1, 1000000)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var last ProgressEvent
+	_, done := scene.RenderContext(ctx, 2, func(event ProgressEvent) {
+		last = event
+		if event.Kind == PassStarted && event.Pass == 1 {
+			cancel()
+		}
+	})
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("%v expected got %v instead", context.Canceled, err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("rendering not stopped after cancellation")
+	}
+
+	if last.Kind != RenderCancelled || last.Pass != 1 || last.AccumulatedRaysPerPixel != 1 {
+		t.Errorf("cancelled event for pass 1 expected got %v/%v/%v instead", last.Kind, last.Pass, last.AccumulatedRaysPerPixel)
+	}
+}
+
+func TestRenderContext_AlreadyCancelled(t *testing.T) {
+	scene := testScene(8, 4, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var kinds []ProgressKind
+	_, done := scene.RenderContext(ctx, 2, func(event ProgressEvent) {
+		kinds = append(kinds, event.Kind)
+	})
+
+	if err := <-done; err != context.Canceled {
+		t.Errorf("%v expected got %v instead", context.Canceled, err)
+	}
+
+	if len(kinds) != 1 || kinds[0] != RenderCancelled {
+		t.Errorf("[%v] expected got %v instead", RenderCancelled, kinds)
+	}
+}
+
+func TestRenderContext_Deterministic(t *testing.T) {
+	render := func(seed int64, parallelCount int, order TileOrder) Pixels {
+		scene := testScene(24, 12, 1, 3)
+		scene.Seed = seed
+		scene.TileSize = 5
+		scene.TileOrder = order
+		fb, done := scene.RenderContext(context.Background(), parallelCount, nil)
+		if err := <-done; err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		pixels, _ := fb.Snapshot(nil)
+		return pixels
+	}
+
+	expected := render(2017, 1, TileScan)
+
+	var tests = []struct {
+		parallelCount int
+		order         TileOrder
+	}{
+		{1, TileSpiral},
+		{3, TileScan},
+		{8, TileHilbert},
+		{64, TileCenterOut},
+	}
+
+	for idx, test := range tests {
+		pixels := render(2017, test.parallelCount, test.order)
+		for k := range expected {
+			if pixels[k] != expected[k] {
+				t.Errorf("pixel %v: %x expected got %x instead [test %v]", k, expected[k], pixels[k], idx)
+				break
+			}
+		}
+	}
+
+	different := render(2018, 4, TileScan)
+	same := true
+	for k := range expected {
+		same = same && different[k] == expected[k]
+	}
+	if same {
+		t.Errorf("a different seed should produce a different image")
+	}
+}
+
+func TestRenderContext_Snapshot(t *testing.T) {
+	scene := testScene(32, 16, 1, 2)
+	scene.TileSize = 4
+
+	fb, done := scene.RenderContext(context.Background(), 4, nil)
+
+	// reads the frame buffer while it is being rendered (go test -race)
+	var pixels Pixels
+	var version uint64
+	for running := true; running; {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			running = false
+		default:
+			var v uint64
+			pixels, v = fb.Snapshot(pixels)
+			if v < version {
+				t.Errorf("version %v should not be smaller than %v", v, version)
+			}
+			version = v
+		}
+	}
+
+	// 2 passes of 8x4 tiles
+	if v := fb.Version(); v != 2*8*4 {
+		t.Errorf("%v expected got %v instead", 2*8*4, v)
+	}
+
+	pixels, _ = fb.Snapshot(pixels)
+	if len(pixels) != 32*16 || pixels[0] != fb.At(0, 0) || pixels[32*16-1] != fb.At(31, 15) {
+		t.Errorf("snapshot does not match the frame buffer")
+	}
+}
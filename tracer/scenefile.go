@@ -0,0 +1,703 @@
+package tracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+/***********************
+ * Scene file
+ ************************/
+// SceneError is an error in a scene file. Path locates the offending value in the document
+// (ex: objects[3].material.albedo)
+type SceneError struct {
+	File string
+	Path string
+	Err  error
+}
+
+func (e *SceneError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%v: %v", e.File, e.Err)
+	}
+	return fmt.Sprintf("%v: %v: %v", e.File, e.Path, e.Err)
+}
+
+// LoadSceneFile loads a scene described in a JSON (.json) or YAML (.yaml/.yml) file (see README.md for the format)
+//	relative paths in the file (models, images) are relative to the file itself
+func LoadSceneFile(path string, width, height int) (Camera, HitableList, Background, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var doc interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+		doc = normalizeYAML(doc)
+	default:
+		err = json.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, nil, nil, &SceneError{File: path, Err: err}
+	}
+
+	decoder := &sceneDecoder{dir: filepath.Dir(path), aspect: float64(width) / float64(height), materials: make(map[string]Material)}
+	camera, world, background, err := decoder.decode(sceneValue{value: doc})
+	if err != nil {
+		if se, ok := err.(*SceneError); ok {
+			se.File = path
+			return nil, nil, nil, se
+		}
+		return nil, nil, nil, &SceneError{File: path, Err: err}
+	}
+
+	return camera, world, background, nil
+}
+
+// normalizeYAML converts the maps decoded by yaml (map[interface{}]interface{}) and the integers into the types
+// decoded by json (map[string]interface{} and float64) so that the same decoder works for both formats
+func normalizeYAML(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[fmt.Sprint(k)] = normalizeYAML(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range t {
+			t[i] = normalizeYAML(e)
+		}
+		return t
+	case int:
+		return float64(t)
+	case int64:
+		return float64(t)
+	case uint64:
+		return float64(t)
+	}
+	return v
+}
+
+/***********************
+ * sceneValue
+ ************************/
+// sceneValue is a value in the (generic) document along with its path (used for errors)
+type sceneValue struct {
+	path  string
+	value interface{}
+}
+
+func (sv sceneValue) errorf(format string, args ...interface{}) error {
+	return &SceneError{Path: sv.path, Err: fmt.Errorf(format, args...)}
+}
+
+// defined returns true if the value is present in the document
+func (sv sceneValue) defined() bool {
+	return sv.value != nil
+}
+
+// field returns the field of an object (undefined if not present)
+func (sv sceneValue) field(name string) sceneValue {
+	path := name
+	if sv.path != "" {
+		path = sv.path + "." + name
+	}
+	m, _ := sv.value.(map[string]interface{})
+	return sceneValue{path, m[name]}
+}
+
+// object checks that the value is an object which only contains the fields provided
+func (sv sceneValue) object(fields ...string) error {
+	m, ok := sv.value.(map[string]interface{})
+	if !ok {
+		return sv.errorf("must be an object")
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		known := false
+		for _, f := range fields {
+			if k == f {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return sv.field(k).errorf("unknown field (must be one of %v)", strings.Join(fields, ", "))
+		}
+	}
+
+	return nil
+}
+
+// list returns the elements of a list
+func (sv sceneValue) list() ([]sceneValue, error) {
+	l, ok := sv.value.([]interface{})
+	if !ok {
+		return nil, sv.errorf("must be a list")
+	}
+	elements := make([]sceneValue, len(l))
+	for i, e := range l {
+		elements[i] = sceneValue{fmt.Sprintf("%v[%v]", sv.path, i), e}
+	}
+	return elements, nil
+}
+
+func (sv sceneValue) str() (string, error) {
+	if !sv.defined() {
+		return "", sv.errorf("missing value")
+	}
+	s, ok := sv.value.(string)
+	if !ok {
+		return "", sv.errorf("must be a string")
+	}
+	return s, nil
+}
+
+func (sv sceneValue) float() (float64, error) {
+	if !sv.defined() {
+		return 0, sv.errorf("missing value")
+	}
+	f, ok := sv.value.(float64)
+	if !ok {
+		return 0, sv.errorf("must be a number")
+	}
+	return f, nil
+}
+
+// floatOr returns the number or def when the value is not defined
+func (sv sceneValue) floatOr(def float64) (float64, error) {
+	if !sv.defined() {
+		return def, nil
+	}
+	return sv.float()
+}
+
+func (sv sceneValue) boolOr(def bool) (bool, error) {
+	if !sv.defined() {
+		return def, nil
+	}
+	b, ok := sv.value.(bool)
+	if !ok {
+		return false, sv.errorf("must be true or false")
+	}
+	return b, nil
+}
+
+// triple returns a list of 3 numbers
+func (sv sceneValue) triple() (float64, float64, float64, error) {
+	if !sv.defined() {
+		return 0, 0, 0, sv.errorf("missing value")
+	}
+	l, ok := sv.value.([]interface{})
+	if !ok || len(l) != 3 {
+		return 0, 0, 0, sv.errorf("must be a list of 3 numbers")
+	}
+	var f [3]float64
+	for i, e := range l {
+		if f[i], ok = e.(float64); !ok {
+			return 0, 0, 0, sv.errorf("must be a list of 3 numbers")
+		}
+	}
+	return f[0], f[1], f[2], nil
+}
+
+func (sv sceneValue) point() (Point3, error) {
+	x, y, z, err := sv.triple()
+	return Point3{x, y, z}, err
+}
+
+func (sv sceneValue) vec3() (Vec3, error) {
+	x, y, z, err := sv.triple()
+	return Vec3{x, y, z}, err
+}
+
+func (sv sceneValue) color() (Color, error) {
+	r, g, b, err := sv.triple()
+	return Color{r, g, b}, err
+}
+
+/***********************
+ * sceneDecoder
+ ************************/
+// sceneDecoder converts the (generic) document into the camera, world and background
+type sceneDecoder struct {
+	dir       string
+	aspect    float64
+	materials map[string]Material
+}
+
+// resolve returns the path relative to the scene file
+func (sd *sceneDecoder) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(sd.dir, path)
+}
+
+func (sd *sceneDecoder) decode(doc sceneValue) (Camera, HitableList, Background, error) {
+	if err := doc.object("camera", "background", "materials", "objects"); err != nil {
+		return nil, nil, nil, err
+	}
+
+	camera, err := sd.camera(doc.field("camera"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var background Background = SkyBackground
+	if bg := doc.field("background"); bg.defined() {
+		if background, err = sd.background(bg); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	// named materials (sorted so that errors are reproducible)
+	if materials := doc.field("materials"); materials.defined() {
+		m, ok := materials.value.(map[string]interface{})
+		if !ok {
+			return nil, nil, nil, materials.errorf("must be an object")
+		}
+		var names []string
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			material, err := sd.material(materials.field(name))
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			sd.materials[name] = material
+		}
+	}
+
+	objects, err := doc.field("objects").list()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	world := HitableList{}
+	for _, object := range objects {
+		hitables, err := sd.object(object)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		world = append(world, hitables...)
+	}
+
+	return camera, world, background, nil
+}
+
+// camera decodes the parameters of NewCamera (focusDist defaults to the distance between lookFrom and lookAt)
+func (sd *sceneDecoder) camera(sv sceneValue) (Camera, error) {
+	if err := sv.object("lookFrom", "lookAt", "vup", "vfov", "aperture", "focusDist"); err != nil {
+		return nil, err
+	}
+
+	lookFrom, err := sv.field("lookFrom").point()
+	if err != nil {
+		return nil, err
+	}
+	lookAt, err := sv.field("lookAt").point()
+	if err != nil {
+		return nil, err
+	}
+	vup := Vec3{Y: 1.0}
+	if sv.field("vup").defined() {
+		if vup, err = sv.field("vup").vec3(); err != nil {
+			return nil, err
+		}
+	}
+	vfov, err := sv.field("vfov").floatOr(20)
+	if err != nil {
+		return nil, err
+	}
+	if vfov <= 0 || vfov >= 180 {
+		return nil, sv.field("vfov").errorf("must be in ]0,180[ (degrees)")
+	}
+	aperture, err := sv.field("aperture").floatOr(0)
+	if err != nil {
+		return nil, err
+	}
+	focusDist, err := sv.field("focusDist").floatOr(lookFrom.Sub(lookAt).Length())
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCamera(lookFrom, lookAt, vup, vfov, sd.aspect, aperture, focusDist), nil
+}
+
+// background decodes a background: either the same string as the -background option or an environment map
+// object with a path, rotation (in degrees) and intensity
+func (sd *sceneDecoder) background(sv sceneValue) (Background, error) {
+	if spec, ok := sv.value.(string); ok {
+		if strings.HasPrefix(spec, "image:") {
+			spec = "image:" + sd.resolve(strings.TrimPrefix(spec, "image:"))
+		}
+		background, err := ParseBackground(spec)
+		if err != nil {
+			return nil, sv.errorf("%v", err)
+		}
+		return background, nil
+	}
+
+	if err := sv.object("path", "rotation", "intensity"); err != nil {
+		return nil, err
+	}
+	path, err := sv.field("path").str()
+	if err != nil {
+		return nil, err
+	}
+	rotation, err := sv.field("rotation").floatOr(0)
+	if err != nil {
+		return nil, err
+	}
+	intensity, err := sv.field("intensity").floatOr(1.0)
+	if err != nil {
+		return nil, err
+	}
+	env, err := LoadEnvironmentMap(sd.resolve(path), rotation, intensity)
+	if err != nil {
+		return nil, sv.field("path").errorf("%v", err)
+	}
+	return env, nil
+}
+
+// material decodes a material: the name of a material defined in materials or an object whose type is one of
+// lambertian (albedo), metal (albedo, fuzz), dielectric (refIdx) or diffuseLight (emit)
+func (sd *sceneDecoder) material(sv sceneValue) (Material, error) {
+	if name, ok := sv.value.(string); ok {
+		material, ok := sd.materials[name]
+		if !ok {
+			return nil, sv.errorf("unknown material [%v]", name)
+		}
+		return material, nil
+	}
+
+	kind, err := sd.kind(sv)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case "lambertian":
+		if err := sv.object("type", "albedo"); err != nil {
+			return nil, err
+		}
+		albedo, err := sd.texture(sv.field("albedo"))
+		if err != nil {
+			return nil, err
+		}
+		return Lambertian{albedo}, nil
+
+	case "metal":
+		if err := sv.object("type", "albedo", "fuzz"); err != nil {
+			return nil, err
+		}
+		albedo, err := sd.texture(sv.field("albedo"))
+		if err != nil {
+			return nil, err
+		}
+		fuzz, err := sv.field("fuzz").floatOr(0)
+		if err != nil {
+			return nil, err
+		}
+		return Metal{albedo, fuzz}, nil
+
+	case "dielectric":
+		if err := sv.object("type", "refIdx"); err != nil {
+			return nil, err
+		}
+		refIdx, err := sv.field("refIdx").floatOr(1.5)
+		if err != nil {
+			return nil, err
+		}
+		return Dielectric{refIdx}, nil
+
+	case "diffuseLight":
+		if err := sv.object("type", "emit"); err != nil {
+			return nil, err
+		}
+		emit, err := sd.texture(sv.field("emit"))
+		if err != nil {
+			return nil, err
+		}
+		return DiffuseLight{emit}, nil
+	}
+
+	return nil, sv.field("type").errorf("unknown material type [%v] (must be one of lambertian, metal, dielectric or diffuseLight)", kind)
+}
+
+// texture decodes a texture: a color ([r, g, b]) or an object whose type is one of checker (odd, even, scale),
+// image (path) or noise (pattern, scale, octaves, c0, c1, seed)
+func (sd *sceneDecoder) texture(sv sceneValue) (Texture, error) {
+	if _, ok := sv.value.([]interface{}); ok || !sv.defined() {
+		return sv.color()
+	}
+
+	kind, err := sd.kind(sv)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case "checker":
+		if err := sv.object("type", "odd", "even", "scale"); err != nil {
+			return nil, err
+		}
+		odd, err := sd.texture(sv.field("odd"))
+		if err != nil {
+			return nil, err
+		}
+		even, err := sd.texture(sv.field("even"))
+		if err != nil {
+			return nil, err
+		}
+		scale, err := sv.field("scale").floatOr(10)
+		if err != nil {
+			return nil, err
+		}
+		return CheckerTexture{Odd: odd, Even: even, Scale: scale}, nil
+
+	case "image":
+		if err := sv.object("type", "path"); err != nil {
+			return nil, err
+		}
+		path, err := sv.field("path").str()
+		if err != nil {
+			return nil, err
+		}
+		texture, err := LoadImageTexture(sd.resolve(path))
+		if err != nil {
+			return nil, sv.field("path").errorf("%v", err)
+		}
+		return texture, nil
+
+	case "noise":
+		if err := sv.object("type", "pattern", "scale", "octaves", "c0", "c1", "seed"); err != nil {
+			return nil, err
+		}
+		pattern := NoiseSmooth
+		if sv.field("pattern").defined() {
+			name, err := sv.field("pattern").str()
+			if err != nil {
+				return nil, err
+			}
+			pattern = -1
+			for i, n := range noisePatternNames {
+				if n == name {
+					pattern = NoisePattern(i)
+				}
+			}
+			if pattern < 0 {
+				return nil, sv.field("pattern").errorf("unknown pattern [%v] (must be one of %v)", name, strings.Join(noisePatternNames, ", "))
+			}
+		}
+		scale, err := sv.field("scale").floatOr(1)
+		if err != nil {
+			return nil, err
+		}
+		octaves, err := sv.field("octaves").floatOr(7)
+		if err != nil {
+			return nil, err
+		}
+		c0, err := sd.colorOr(sv.field("c0"), Black)
+		if err != nil {
+			return nil, err
+		}
+		c1, err := sd.colorOr(sv.field("c1"), White)
+		if err != nil {
+			return nil, err
+		}
+		// the noise is generated from the seed so that the result is reproducible
+		seed, err := sv.field("seed").floatOr(float64(rand.Int63()))
+		if err != nil {
+			return nil, err
+		}
+		perlin := NewPerlin(rand.New(rand.NewSource(int64(seed))))
+		return NewNoiseTexture(perlin, pattern, scale, int(octaves), c0, c1), nil
+	}
+
+	return nil, sv.field("type").errorf("unknown texture type [%v] (must be one of checker, image or noise)", kind)
+}
+
+func (sd *sceneDecoder) colorOr(sv sceneValue, def Color) (Color, error) {
+	if !sv.defined() {
+		return def, nil
+	}
+	return sv.color()
+}
+
+// kind returns the type of an object
+func (sd *sceneDecoder) kind(sv sceneValue) (string, error) {
+	if _, ok := sv.value.(map[string]interface{}); !ok {
+		if !sv.defined() {
+			return "", sv.errorf("missing value")
+		}
+		return "", sv.errorf("must be an object")
+	}
+	return sv.field("type").str()
+}
+
+// object decodes an object whose type is one of sphere (center, radius), xyRect (x0, x1, y0, y1, k),
+// xzRect (x0, x1, z0, z1, k), yzRect (y0, y1, z0, z1, k), box (p0, p1), triangle (vertices) or model (path).
+// All objects (except models which use their own materials) have a material. Rectangles can be flipped.
+func (sd *sceneDecoder) object(sv sceneValue) (HitableList, error) {
+	kind, err := sd.kind(sv)
+	if err != nil {
+		return nil, err
+	}
+
+	if kind == "model" {
+		if err := sv.object("type", "path"); err != nil {
+			return nil, err
+		}
+		path, err := sv.field("path").str()
+		if err != nil {
+			return nil, err
+		}
+		model, err := LoadModel(sd.resolve(path))
+		if err != nil {
+			return nil, sv.field("path").errorf("%v", err)
+		}
+		return model, nil
+	}
+
+	// floats reads the numeric fields in order
+	floats := func(names ...string) ([]float64, error) {
+		values := make([]float64, len(names))
+		for i, name := range names {
+			v, err := sv.field(name).float()
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		return values, nil
+	}
+
+	// rect decodes the fields common to all rectangles
+	rect := func(a, b string) ([]float64, Material, bool, error) {
+		if err := sv.object("type", a+"0", a+"1", b+"0", b+"1", "k", "material", "flip"); err != nil {
+			return nil, nil, false, err
+		}
+		values, err := floats(a+"0", a+"1", b+"0", b+"1", "k")
+		if err != nil {
+			return nil, nil, false, err
+		}
+		material, err := sd.material(sv.field("material"))
+		if err != nil {
+			return nil, nil, false, err
+		}
+		flip, err := sv.field("flip").boolOr(false)
+		return values, material, flip, err
+	}
+
+	// flipped wraps the rectangle when flip is true
+	flipped := func(hitable Hitable, flip bool) HitableList {
+		if flip {
+			return HitableList{FlipNormals{hitable}}
+		}
+		return HitableList{hitable}
+	}
+
+	switch kind {
+	case "sphere":
+		if err := sv.object("type", "center", "radius", "material"); err != nil {
+			return nil, err
+		}
+		center, err := sv.field("center").point()
+		if err != nil {
+			return nil, err
+		}
+		radius, err := sv.field("radius").float()
+		if err != nil {
+			return nil, err
+		}
+		material, err := sd.material(sv.field("material"))
+		if err != nil {
+			return nil, err
+		}
+		return HitableList{Sphere{Center: center, Radius: radius, Material: material}}, nil
+
+	case "xyRect":
+		v, material, flip, err := rect("x", "y")
+		if err != nil {
+			return nil, err
+		}
+		return flipped(XYRect{v[0], v[1], v[2], v[3], v[4], material}, flip), nil
+
+	case "xzRect":
+		v, material, flip, err := rect("x", "z")
+		if err != nil {
+			return nil, err
+		}
+		return flipped(XZRect{v[0], v[1], v[2], v[3], v[4], material}, flip), nil
+
+	case "yzRect":
+		v, material, flip, err := rect("y", "z")
+		if err != nil {
+			return nil, err
+		}
+		return flipped(YZRect{v[0], v[1], v[2], v[3], v[4], material}, flip), nil
+
+	case "box":
+		if err := sv.object("type", "p0", "p1", "material"); err != nil {
+			return nil, err
+		}
+		p0, err := sv.field("p0").point()
+		if err != nil {
+			return nil, err
+		}
+		p1, err := sv.field("p1").point()
+		if err != nil {
+			return nil, err
+		}
+		material, err := sd.material(sv.field("material"))
+		if err != nil {
+			return nil, err
+		}
+		return NewBox(p0, p1, material), nil
+
+	case "triangle":
+		if err := sv.object("type", "vertices", "material"); err != nil {
+			return nil, err
+		}
+		vertices, err := sv.field("vertices").list()
+		if err != nil {
+			return nil, err
+		}
+		if len(vertices) != 3 {
+			return nil, sv.field("vertices").errorf("must be a list of 3 points")
+		}
+		var p [3]Point3
+		for i, vertex := range vertices {
+			if p[i], err = vertex.point(); err != nil {
+				return nil, err
+			}
+		}
+		material, err := sd.material(sv.field("material"))
+		if err != nil {
+			return nil, err
+		}
+		return HitableList{NewTriangle(p[0], p[1], p[2], material)}, nil
+	}
+
+	return nil, sv.field("type").errorf("unknown object type [%v] (must be one of sphere, xyRect, xzRect, yzRect, box, triangle or model)", kind)
+}
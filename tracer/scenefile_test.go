@@ -0,0 +1,75 @@
+package tracer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadSceneFile_Examples(t *testing.T) {
+	for _, file := range []string{"../scenes/dielectrics.yaml", "../scenes/cornell-box.json"} {
+		camera, world, background, err := LoadSceneFile(file, 200, 100)
+		if err != nil {
+			t.Fatalf("%v [test %v]", err, file)
+		}
+		if camera == nil || background == nil || len(world) == 0 {
+			t.Errorf("incomplete scene %v/%v/%v [test %v]", camera, len(world), background, file)
+		}
+	}
+
+	// the yaml scene is the end result of chapter 10 (with a checkerboard ground)
+	_, world, _, _ := LoadSceneFile("../scenes/dielectrics.yaml", 200, 100)
+	if sphere, ok := world[4].(Sphere); !ok || sphere.Radius != -0.45 || sphere.Material != (Dielectric{1.5}) {
+		t.Errorf("unexpected object %v", world[4])
+	}
+}
+
+func TestLoadSceneFile_Errors(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"albedo.yaml": `
+camera: {lookFrom: [0, 0, 1], lookAt: [0, 0, 0]}
+objects:
+  - {type: sphere, center: [0, 0, 0], radius: 1, material: {type: lambertian, albedo: [1, 0, 0]}}
+  - {type: sphere, center: [0, 0, 0], radius: 1, material: {type: lambertian, albedo: [1, 0]}}
+`,
+		"material.json": `{"camera": {"lookFrom": [0, 0, 1], "lookAt": [0, 0, 0]},
+"objects": [{"type": "box", "p0": [0, 0, 0], "p1": [1, 1, 1], "material": "gold"}]}`,
+		"field.json": `{"camera": {"lookFrom": [0, 0, 1], "lookAt": [0, 0, 0], "fov": 20}, "objects": []}`,
+		"texture.yml": `
+camera: {lookFrom: [0, 0, 1], lookAt: [0, 0, 0]}
+materials:
+  ground: {type: lambertian, albedo: {type: checker, odd: [0, 0, 0], even: black}}
+objects: []
+`,
+		"type.yaml": `
+camera: {lookFrom: [0, 0, 1], lookAt: [0, 0, 0]}
+objects:
+  - {type: cylinder}
+`,
+		"radius.json": `{"camera": {"lookFrom": [0, 0, 1], "lookAt": [0, 0, 0]},
+"objects": [{"type": "sphere", "center": [0, 0, 0], "radius": "big", "material": {"type": "dielectric"}}]}`,
+		"syntax.json": `{"camera": `,
+	})
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{"albedo.yaml", "objects[1].material.albedo: must be a list of 3 numbers"},
+		{"material.json", "objects[0].material: unknown material [gold]"},
+		{"field.json", "camera.fov: unknown field (must be one of lookFrom, lookAt, vup, vfov, aperture, focusDist)"},
+		{"texture.yml", "materials.ground.albedo.even: must be an object"},
+		{"type.yaml", "objects[0].type: unknown object type [cylinder] (must be one of sphere, xyRect, xzRect, yzRect, box, triangle or model)"},
+		{"radius.json", "objects[0].radius: must be a number"},
+		{"syntax.json", "unexpected end of JSON input"},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(dir, test.file)
+		_, _, _, err := LoadSceneFile(path, 200, 100)
+		if err == nil || err.Error() != path+": "+test.expected {
+			t.Errorf("%v != %v [test %v]", err, test.expected, test.file)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package tracer
+
+import "math"
+
+// Sphere is defined by its center and radius (a negative radius makes the normals point inward, ex: hollow glass)
+type Sphere struct {
+	Center   Point3
+	Radius   float64
+	Material Material
+}
+
+// Hit implements the hit interface for a Sphere
+func (s Sphere) Hit(r *Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	oc := r.Origin.Sub(s.Center)         // A-C
+	a := Dot(r.Direction, r.Direction)   // dot(B, B)
+	b := Dot(oc, r.Direction)            // dot(A-C, B)
+	c := Dot(oc, oc) - s.Radius*s.Radius // dot(A-C, A-C) - R*R
+	discriminant := b*b - a*c
+
+	if discriminant > 0 {
+		discriminantSquareRoot := math.Sqrt(discriminant)
+
+		temp := (-b - discriminantSquareRoot) / a
+		if temp < tMax && temp > tMin {
+			hitPoint := r.PointAt(temp)
+			u, v := s.uv(hitPoint)
+			hr := HitRecord{
+				T:        temp,
+				P:        hitPoint,
+				Normal:   hitPoint.Sub(s.Center).Scale(1 / s.Radius),
+				Material: s.Material,
+				U:        u,
+				V:        v,
+			}
+			return true, &hr
+		}
+
+		temp = (-b + discriminantSquareRoot) / a
+		if temp < tMax && temp > tMin {
+			hitPoint := r.PointAt(temp)
+			u, v := s.uv(hitPoint)
+			hr := HitRecord{
+				T:        temp,
+				P:        hitPoint,
+				Normal:   hitPoint.Sub(s.Center).Scale(1 / s.Radius),
+				Material: s.Material,
+				U:        u,
+				V:        v,
+			}
+			return true, &hr
+		}
+	}
+
+	return false, nil
+
+}
+
+// uv computes the surface coordinates of a point on the sphere: u is the longitude (starting at -X) and v the
+// latitude (0 at the bottom)
+func (s Sphere) uv(p Point3) (float64, float64) {
+	d := p.Sub(s.Center).Scale(1 / math.Abs(s.Radius))
+	phi := math.Atan2(d.Z, d.X)
+	theta := math.Asin(math.Max(-1.0, math.Min(1.0, d.Y)))
+	return 1 - (phi+math.Pi)/(2*math.Pi), (theta + math.Pi/2) / math.Pi
+}
+
+// BoundingBox implements the Hitable interface for a Sphere (note that radius can be negative for hollow spheres)
+func (s Sphere) BoundingBox() (bool, *AABB) {
+	r := math.Abs(s.Radius)
+	return true, &AABB{
+		Min: s.Center.Translate(Vec3{-r, -r, -r}),
+		Max: s.Center.Translate(Vec3{r, r, r}),
+	}
+}
@@ -0,0 +1,59 @@
+package tracer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSphere_UV(t *testing.T) {
+	// u is the longitude (0 at -X going toward -Z) and v the latitude (0 at the bottom)
+	var tests = []struct {
+		sphere Sphere
+		p      Point3
+		u, v   float64
+	}{
+		{Sphere{Radius: 1}, Point3{0, -1, 0}, 0.5, 0},
+		{Sphere{Radius: 1}, Point3{0, 1, 0}, 0.5, 1},
+		{Sphere{Radius: 1}, Point3{-1, 0, 0}, 0, 0.5},
+		{Sphere{Radius: 1}, Point3{0, 0, -1}, 0.75, 0.5},
+		{Sphere{Radius: 1}, Point3{1, 0, 0}, 0.5, 0.5},
+		{Sphere{Radius: 1}, Point3{0, 0, 1}, 0.25, 0.5},
+		{Sphere{Radius: 1}, Point3{math.Sqrt2 / 2, math.Sqrt2 / 2, 0}, 0.5, 0.75},
+		// the center and radius (even negative) do not matter
+		{Sphere{Center: Point3{1, 2, 3}, Radius: 2}, Point3{1, 4, 3}, 0.5, 1},
+		{Sphere{Center: Point3{1, 2, 3}, Radius: -2}, Point3{1, 2, 1}, 0.75, 0.5},
+	}
+
+	for idx, test := range tests {
+		if u, v := test.sphere.uv(test.p); !floatEquals(u, test.u) || !floatEquals(v, test.v) {
+			t.Errorf("%v/%v expected got %v/%v instead [test %v]", test.u, test.v, u, v, idx)
+		}
+	}
+}
+
+func TestSphere_HitUV(t *testing.T) {
+	sphere := Sphere{Radius: 1, Material: Lambertian{White}}
+
+	// rays hitting the poles and the equator from outside
+	var tests = []struct {
+		origin    Point3
+		direction Vec3
+		u, v      float64
+	}{
+		{Point3{0, 10, 0}, Vec3{0, -1, 0}, 0.5, 1},
+		{Point3{0, -10, 0}, Vec3{0, 1, 0}, 0.5, 0},
+		{Point3{10, 0, 0}, Vec3{-1, 0, 0}, 0.5, 0.5},
+		{Point3{0, 0, 10}, Vec3{0, 0, -1}, 0.25, 0.5},
+		{Point3{0, 0, -10}, Vec3{0, 0, 1}, 0.75, 0.5},
+	}
+
+	for idx, test := range tests {
+		hit, hr := sphere.Hit(&Ray{Origin: test.origin, Direction: test.direction}, 0.001, math.MaxFloat64)
+		if !hit {
+			t.Fatalf("hit expected [test %v]", idx)
+		}
+		if !floatEquals(hr.U, test.u) || !floatEquals(hr.V, test.v) {
+			t.Errorf("%v/%v expected got %v/%v instead [test %v]", test.u, test.v, hr.U, hr.V, idx)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package tracer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+)
+
+/***********************
+ * STL
+ ************************/
+// LoadSTL loads a STL file (ascii or binary) as a mesh using a gray Lambertian material. The triangles of a STL
+// file are independent so identical vertices are merged to create the indexed mesh (flat shaded). The solids of an
+// ascii file which contains several of them are concatenated in the same mesh
+func LoadSTL(path string) (*Mesh, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := &stlMeshBuilder{vertices: make(map[Point3]int)}
+
+	// binary files can also start with "solid" so the size is checked first
+	if isBinarySTL(data) {
+		err = readBinarySTL(data, builder)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", path, err)
+		}
+	} else {
+		if err = readASCIISTL(path, data, builder); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewMesh(builder.positions, nil, nil, builder.indices, defaultModelMaterial), nil
+}
+
+// stlMeshBuilder merges identical vertices
+type stlMeshBuilder struct {
+	vertices  map[Point3]int
+	positions []Point3
+	indices   []int
+}
+
+func (b *stlMeshBuilder) add(p Point3) {
+	index, ok := b.vertices[p]
+	if !ok {
+		index = len(b.positions)
+		b.vertices[p] = index
+		b.positions = append(b.positions, p)
+	}
+	b.indices = append(b.indices, index)
+}
+
+// isBinarySTL checks whether the size matches the number of triangles (80 bytes header, 4 bytes count and
+// 50 bytes per triangle)
+func isBinarySTL(data []byte) bool {
+	if len(data) < 84 {
+		return false
+	}
+	count := binary.LittleEndian.Uint32(data[80:84])
+	return uint64(len(data)) == 84+50*uint64(count)
+}
+
+func readBinarySTL(data []byte, builder *stlMeshBuilder) error {
+	count := int(binary.LittleEndian.Uint32(data[80:84]))
+
+	vector := func(b []byte) Point3 {
+		return Point3{
+			float64(math.Float32frombits(binary.LittleEndian.Uint32(b[0:4]))),
+			float64(math.Float32frombits(binary.LittleEndian.Uint32(b[4:8]))),
+			float64(math.Float32frombits(binary.LittleEndian.Uint32(b[8:12]))),
+		}
+	}
+
+	for i := 0; i < count; i++ {
+		// normal (12 bytes), 3 vertices (36 bytes) and attribute (2 bytes)
+		triangle := data[84+i*50 : 84+(i+1)*50]
+		builder.add(vector(triangle[12:24]))
+		builder.add(vector(triangle[24:36]))
+		builder.add(vector(triangle[36:48]))
+	}
+
+	return nil
+}
+
+func readASCIISTL(path string, data []byte, builder *stlMeshBuilder) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	vertices := 0
+	inSolid := false
+
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "solid":
+			// several solids are concatenated in the same mesh
+			if inSolid {
+				return &ParseError{path, line, fmt.Errorf("unexpected solid (missing endsolid)")}
+			}
+			inSolid = true
+		case "endsolid":
+			inSolid = false
+		case "vertex":
+			v, err := parseFloats(fields[1:], 3)
+			if err != nil {
+				return &ParseError{path, line, err}
+			}
+			builder.add(Point3{v[0], v[1], v[2]})
+			vertices++
+		case "endloop":
+			if vertices != 3 {
+				return &ParseError{path, line, fmt.Errorf("facet with %v vertices (expected 3)", vertices)}
+			}
+			vertices = 0
+		case "facet", "outer", "endfacet":
+		default:
+			return &ParseError{path, line, fmt.Errorf("unknown keyword [%v]", fields[0])}
+		}
+
+		if line == 1 && fields[0] != "solid" {
+			return &ParseError{path, line, fmt.Errorf("not a stl file (missing solid)")}
+		}
+	}
+
+	if err := scanner.Err(); err != nil && err != io.EOF {
+		return &ParseError{path, line, err}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package tracer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadSTL(t *testing.T) {
+	var buf bytes.Buffer
+	// binary header starting with solid (which must not be mistaken for ascii)
+	header := make([]byte, 80)
+	copy(header, "solid binary")
+	buf.Write(header)
+	binary.Write(&buf, binary.LittleEndian, uint32(2))
+	for _, triangle := range [][]float32{{0, 0, 0, 1, 0, 0, 1, 1, 0}, {0, 0, 0, 1, 1, 0, 0, 1, 0}} {
+		binary.Write(&buf, binary.LittleEndian, []float32{0, 0, 1})
+		binary.Write(&buf, binary.LittleEndian, triangle)
+		binary.Write(&buf, binary.LittleEndian, uint16(0))
+	}
+
+	dir := writeTestFiles(t, map[string]string{
+		"ascii.stl": `solid ascii
+facet normal 0 0 1
+  outer loop
+    vertex 0 0 0
+    vertex 1 0 0
+    vertex 1 1 0
+  endloop
+endfacet
+endsolid ascii
+`,
+		"solids.stl": `solid first
+facet normal 0 0 1
+  outer loop
+    vertex 0 0 0
+    vertex 1 0 0
+    vertex 1 1 0
+  endloop
+endfacet
+endsolid first
+solid second
+facet normal 0 0 1
+  outer loop
+    vertex 0 0 0
+    vertex 1 1 0
+    vertex 0 1 0
+  endloop
+endfacet
+endsolid second
+`,
+		"nested.stl": "solid first\nsolid second\nendsolid second\nendsolid first\n",
+	})
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "binary.stl"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mesh, err := LoadSTL(filepath.Join(dir, "binary.stl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// identical vertices are merged
+	if mesh.TriangleCount() != 2 || len(mesh.vertices) != 4 {
+		t.Errorf("unexpected mesh %v triangles %v vertices", mesh.TriangleCount(), len(mesh.vertices))
+	}
+
+	mesh, err = LoadSTL(filepath.Join(dir, "ascii.stl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mesh.TriangleCount() != 1 || len(mesh.vertices) != 3 {
+		t.Errorf("unexpected mesh %v triangles %v vertices", mesh.TriangleCount(), len(mesh.vertices))
+	}
+
+	// several solids are concatenated
+	mesh, err = LoadSTL(filepath.Join(dir, "solids.stl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mesh.TriangleCount() != 2 || len(mesh.vertices) != 4 {
+		t.Errorf("unexpected mesh %v triangles %v vertices", mesh.TriangleCount(), len(mesh.vertices))
+	}
+
+	_, err = LoadSTL(filepath.Join(dir, "nested.stl"))
+	if pe, ok := err.(*ParseError); !ok || pe.Line != 2 {
+		t.Errorf("expected error at line 2 got %v instead", err)
+	}
+}
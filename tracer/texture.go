@@ -0,0 +1,81 @@
+package tracer
+
+import (
+	"math"
+)
+
+/***********************
+ * Texture
+ ************************/
+// Texture defines the color of a material at a given hit point
+//	u,v are the surface coordinates of the hit point (in [0,1])
+//	p is the hit point itself (used by 3D/solid textures)
+// Note that Color implements Texture (solid color) so a Color can be used wherever a Texture is expected
+type Texture interface {
+	Value(u, v float64, p Point3) Color
+}
+
+// Value implements the Texture interface for a Color (solid color)
+func (c Color) Value(u, v float64, p Point3) Color {
+	return c
+}
+
+/***********************
+ * CheckerTexture
+ ************************/
+// CheckerTexture is a 3D checker alternating between 2 textures
+//	scale defines the frequency of the checker (the size of a square is pi / scale)
+type CheckerTexture struct {
+	Odd, Even Texture
+	Scale     float64
+}
+
+func (ct CheckerTexture) Value(u, v float64, p Point3) Color {
+	sines := math.Sin(ct.Scale*p.X) * math.Sin(ct.Scale*p.Y) * math.Sin(ct.Scale*p.Z)
+	if sines < 0 {
+		return ct.Odd.Value(u, v, p)
+	}
+	return ct.Even.Value(u, v, p)
+}
+
+/***********************
+ * ImageTexture
+ ************************/
+// ImageTexture maps an image using the u,v coordinates (0,0 is the bottom left corner of the image)
+type ImageTexture struct {
+	image *HDRImage
+}
+
+// NewImageTexture creates a texture from an image
+func NewImageTexture(image *HDRImage) *ImageTexture {
+	return &ImageTexture{image}
+}
+
+// LoadImageTexture loads an image (png, jpeg, but also Radiance .hdr or .pfm) to use as a texture
+func LoadImageTexture(path string) (*ImageTexture, error) {
+	image, err := LoadHDRImage(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewImageTexture(image), nil
+}
+
+func (it *ImageTexture) Value(u, v float64, p Point3) Color {
+	x := int(u * float64(it.image.Width))
+	y := int((1 - v) * float64(it.image.Height))
+
+	if x < 0 {
+		x = 0
+	}
+	if y < 0 {
+		y = 0
+	}
+	if x >= it.image.Width {
+		x = it.image.Width - 1
+	}
+	if y >= it.image.Height {
+		y = it.image.Height - 1
+	}
+
+	return it.image.At(x, y)
+}
@@ -0,0 +1,58 @@
+package tracer
+
+import "testing"
+
+func TestCheckerTexture(t *testing.T) {
+	odd, even := Color{R: 1}, Color{G: 1}
+	checker := CheckerTexture{Odd: odd, Even: even, Scale: 3.141592653589793}
+
+	// with a scale of pi, the squares are 1 unit wide and change at integer coordinates
+	var tests = []struct {
+		p        Point3
+		expected Color
+	}{
+		{Point3{0.5, 0.5, 0.5}, even},
+		{Point3{1.5, 0.5, 0.5}, odd},
+		{Point3{1.5, 1.5, 0.5}, even},
+		{Point3{1.5, 1.5, 1.5}, odd},
+		{Point3{-0.5, 0.5, 0.5}, odd},
+		{Point3{-0.5, -0.5, 0.5}, even},
+		{Point3{2.5, 0.5, 0.5}, even},
+	}
+
+	for idx, test := range tests {
+		if c := checker.Value(0, 0, test.p); c != test.expected {
+			t.Errorf("%v expected got %v instead [test %v]", test.expected, c, idx)
+		}
+	}
+}
+
+func TestImageTexture(t *testing.T) {
+	// 2x2 image (row by row from the top)
+	topLeft, topRight := Color{R: 1}, Color{G: 1}
+	bottomLeft, bottomRight := Color{B: 1}, White
+	texture := NewImageTexture(&HDRImage{Width: 2, Height: 2, Pixels: []Color{topLeft, topRight, bottomLeft, bottomRight}})
+
+	// v = 0 is the bottom of the image (the image is flipped vertically) and the coordinates are clamped
+	var tests = []struct {
+		u, v     float64
+		expected Color
+	}{
+		{0, 0, bottomLeft},
+		{1, 0, bottomRight},
+		{0, 1, topLeft},
+		{1, 1, topRight},
+		{0.25, 0.75, topLeft},
+		{0.75, 0.25, bottomRight},
+		{0.49, 0.51, topLeft},
+		{0.51, 0.49, bottomRight},
+		{-1, 2, topLeft},
+		{2, -1, bottomRight},
+	}
+
+	for idx, test := range tests {
+		if c := texture.Value(test.u, test.v, Point3{}); c != test.expected {
+			t.Errorf("%v expected got %v instead [test %v]", test.expected, c, idx)
+		}
+	}
+}
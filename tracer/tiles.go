@@ -0,0 +1,178 @@
+package tracer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultTileSize is the size (in pixels) of the tiles when Scene.TileSize is not set
+const DefaultTileSize = 32
+
+// Tile is a rectangular area of the image [X0,X1) x [Y0,Y1) in image coordinates (y = 0 is the top row)
+type Tile struct {
+	X0, Y0, X1, Y1 int
+}
+
+// TileOrder defines in which order the tiles of the image are rendered
+type TileOrder int
+
+const (
+	// TileScan renders the tiles row by row, from the top left corner to the bottom right corner
+	TileScan TileOrder = iota
+	// TileSpiral renders the tiles following a square spiral starting from the center of the image
+	TileSpiral
+	// TileHilbert renders the tiles following a Hilbert curve (consecutive tiles are neighbors)
+	TileHilbert
+	// TileCenterOut renders the tiles closest to the center of the image first
+	TileCenterOut
+)
+
+var tileOrderNames = []string{"scan", "spiral", "hilbert", "center-out"}
+
+func (o TileOrder) String() string {
+	if o < 0 || int(o) >= len(tileOrderNames) {
+		return fmt.Sprintf("TileOrder(%d)", int(o))
+	}
+	return tileOrderNames[o]
+}
+
+// Set allows TileOrder to be used on the command line (flag)
+// Example: ray-tracing -tile-order hilbert
+func (o *TileOrder) Set(value string) error {
+	for i, name := range tileOrderNames {
+		if value == name {
+			*o = TileOrder(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown tile order [%v] (must be one of %v)", value, strings.Join(tileOrderNames, ", "))
+}
+
+// Tiles splits the image (width x height) in tiles of tileSize x tileSize pixels (the tiles on the right and bottom
+// edges may be smaller) and returns them in the requested order. Every pixel belongs to exactly one tile.
+func Tiles(width, height, tileSize int, order TileOrder) []Tile {
+	if width <= 0 || height <= 0 {
+		return nil
+	}
+
+	if tileSize <= 0 {
+		tileSize = DefaultTileSize
+	}
+
+	nx := (width + tileSize - 1) / tileSize
+	ny := (height + tileSize - 1) / tileSize
+
+	tile := func(i, j int) Tile {
+		return Tile{
+			X0: i * tileSize,
+			Y0: j * tileSize,
+			X1: minInt((i+1)*tileSize, width),
+			Y1: minInt((j+1)*tileSize, height),
+		}
+	}
+
+	var grid [][2]int
+	switch order {
+	case TileSpiral:
+		grid = spiralOrder(nx, ny)
+	case TileHilbert:
+		grid = hilbertOrder(nx, ny)
+	default:
+		grid = scanOrder(nx, ny)
+	}
+
+	tiles := make([]Tile, len(grid))
+	for k, ij := range grid {
+		tiles[k] = tile(ij[0], ij[1])
+	}
+
+	if order == TileCenterOut {
+		// distance (squared) from the center of the tile to the center of the image (stable => ties in scan order)
+		distance := func(t Tile) float64 {
+			dx := float64(t.X0+t.X1-width) / 2.0
+			dy := float64(t.Y0+t.Y1-height) / 2.0
+			return dx*dx + dy*dy
+		}
+		sort.SliceStable(tiles, func(a, b int) bool { return distance(tiles[a]) < distance(tiles[b]) })
+	}
+
+	return tiles
+}
+
+// scanOrder returns the (i,j) coordinates of the tiles of a nx x ny grid row by row
+func scanOrder(nx, ny int) [][2]int {
+	grid := make([][2]int, 0, nx*ny)
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			grid = append(grid, [2]int{i, j})
+		}
+	}
+	return grid
+}
+
+// spiralOrder returns the (i,j) coordinates of the tiles of a nx x ny grid following a square spiral starting from
+// the center (legs of length 1, 1, 2, 2, 3, 3... turning clockwise) skipping the positions outside the grid
+func spiralOrder(nx, ny int) [][2]int {
+	grid := make([][2]int, 0, nx*ny)
+	directions := [][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+
+	i, j := (nx-1)/2, (ny-1)/2
+	grid = append(grid, [2]int{i, j})
+
+	for leg := 0; len(grid) < nx*ny; leg++ {
+		d := directions[leg%4]
+		for step := 0; step < leg/2+1; step++ {
+			i, j = i+d[0], j+d[1]
+			if i >= 0 && i < nx && j >= 0 && j < ny {
+				grid = append(grid, [2]int{i, j})
+			}
+		}
+	}
+
+	return grid
+}
+
+// hilbertOrder returns the (i,j) coordinates of the tiles of a nx x ny grid following the Hilbert curve covering
+// the smallest power of 2 square containing the grid (skipping the positions outside the grid)
+func hilbertOrder(nx, ny int) [][2]int {
+	n := 1
+	for n < nx || n < ny {
+		n *= 2
+	}
+
+	grid := make([][2]int, 0, nx*ny)
+	for d := 0; d < n*n; d++ {
+		i, j := hilbertD2XY(n, d)
+		if i < nx && j < ny {
+			grid = append(grid, [2]int{i, j})
+		}
+	}
+	return grid
+}
+
+// hilbertD2XY converts the distance d along the Hilbert curve of a n x n square (n power of 2) into x/y coordinates
+func hilbertD2XY(n, d int) (int, int) {
+	x, y := 0, 0
+	for s := 1; s < n; s *= 2 {
+		rx := 1 & (d / 2)
+		ry := 1 & (d ^ rx)
+		if ry == 0 {
+			if rx == 1 {
+				x, y = s-1-x, s-1-y
+			}
+			x, y = y, x
+		}
+		x += s * rx
+		y += s * ry
+		d /= 4
+	}
+	return x, y
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,81 @@
+package tracer
+
+import (
+	"testing"
+)
+
+func TestTiles_Coverage(t *testing.T) {
+	var tests = []struct {
+		width, height, tileSize int
+	}{
+		{800, 400, 32},
+		{100, 37, 16},
+		{5, 3, 8},
+		{64, 64, 0},
+		{33, 100, 7},
+	}
+
+	for idx, test := range tests {
+		for order := TileScan; order <= TileCenterOut; order++ {
+			counts := make([]int, test.width*test.height)
+			for _, tile := range Tiles(test.width, test.height, test.tileSize, order) {
+				if tile.X0 >= tile.X1 || tile.Y0 >= tile.Y1 {
+					t.Errorf("empty tile %v [test %v/%v]", tile, idx, order)
+				}
+				for y := tile.Y0; y < tile.Y1; y++ {
+					for x := tile.X0; x < tile.X1; x++ {
+						counts[y*test.width+x]++
+					}
+				}
+			}
+			for k, count := range counts {
+				if count != 1 {
+					t.Errorf("pixel %v covered %v times [test %v/%v]", k, count, idx, order)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestTiles_Order(t *testing.T) {
+	contains := func(tile Tile, x, y int) bool {
+		return x >= tile.X0 && x < tile.X1 && y >= tile.Y0 && y < tile.Y1
+	}
+
+	// the center of the image is rendered first
+	for _, order := range []TileOrder{TileSpiral, TileCenterOut} {
+		tiles := Tiles(800, 400, 32, order)
+		if !contains(tiles[0], 400, 200) && !contains(tiles[0], 399, 199) {
+			t.Errorf("first tile %v does not contain the center [test %v]", tiles[0], order)
+		}
+	}
+
+	// scan starts at the top left corner and ends at the bottom right corner
+	tiles := Tiles(100, 50, 16, TileScan)
+	if tiles[0] != (Tile{0, 0, 16, 16}) || tiles[len(tiles)-1] != (Tile{96, 48, 100, 50}) {
+		t.Errorf("unexpected scan order %v", tiles)
+	}
+
+	// consecutive tiles of a Hilbert curve are neighbors
+	tiles = Tiles(256, 256, 32, TileHilbert)
+	for k := 1; k < len(tiles); k++ {
+		dx, dy := tiles[k].X0-tiles[k-1].X0, tiles[k].Y0-tiles[k-1].Y0
+		if dx*dx+dy*dy != 32*32 {
+			t.Errorf("%v and %v are not neighbors", tiles[k-1], tiles[k])
+		}
+	}
+}
+
+func TestTileOrder_Set(t *testing.T) {
+	var order TileOrder
+	for _, expected := range []TileOrder{TileScan, TileSpiral, TileHilbert, TileCenterOut} {
+		if err := order.Set(expected.String()); err != nil || order != expected {
+			t.Errorf("%v expected got %v (%v) instead", expected, order, err)
+		}
+	}
+
+	if err := order.Set("random"); err == nil {
+		t.Errorf("error expected for unknown order")
+	}
+}
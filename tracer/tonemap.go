@@ -0,0 +1,131 @@
+package tracer
+
+import (
+	"fmt"
+	"math"
+)
+
+/***********************
+ * ToneMapper
+ ************************/
+// ToneMapper maps a linear (unclamped) color, after exposure, to a displayable color in [0,1] (before gamma
+// correction). It is applied to the live preview and the saved (8 bits) image while HDR output is left untouched.
+type ToneMapper interface {
+	ToneMap(c Color) Color
+}
+
+// ToneMapperNames lists the names accepted by ParseToneMapper
+var ToneMapperNames = []string{"clamp", "reinhard", "reinhard-extended", "aces", "hable"}
+
+const (
+	// DefaultReinhardWhite is the white point of ExtendedReinhardToneMapper when not provided
+	DefaultReinhardWhite = 4.0
+	// DefaultHableWhite is the white point of HableToneMapper when not provided (value of the original curve)
+	DefaultHableWhite = 11.2
+)
+
+// ParseToneMapper returns the tone mapper matching the name (see ToneMapperNames). white is the white point (the
+// smallest linear value mapped to 1) of the operators which use one (a default is used when <= 0)
+func ParseToneMapper(name string, white float64) (ToneMapper, error) {
+	switch name {
+	case "clamp":
+		return ClampToneMapper{}, nil
+	case "reinhard":
+		return ReinhardToneMapper{}, nil
+	case "reinhard-extended":
+		if white <= 0 {
+			white = DefaultReinhardWhite
+		}
+		return ExtendedReinhardToneMapper{White: white}, nil
+	case "aces":
+		return ACESToneMapper{}, nil
+	case "hable":
+		if white <= 0 {
+			white = DefaultHableWhite
+		}
+		return HableToneMapper{White: white}, nil
+	}
+	return nil, fmt.Errorf("unknown tone mapper [%v] (must be one of %v)", name, ToneMapperNames)
+}
+
+// ExposureScale converts an exposure in stops into the factor applied to the colors (each stop doubles the light)
+func ExposureScale(stops float64) float64 {
+	return math.Exp2(stops)
+}
+
+// ClampToneMapper simply clamps each component to [0,1] (bright areas blow out)
+type ClampToneMapper struct{}
+
+func (tm ClampToneMapper) ToneMap(c Color) Color {
+	return Color{R: clamp01(c.R), G: clamp01(c.G), B: clamp01(c.B)}
+}
+
+// ReinhardToneMapper compresses the luminance L into L / (1 + L) (never reaches white, preserves hue)
+type ReinhardToneMapper struct{}
+
+func (tm ReinhardToneMapper) ToneMap(c Color) Color {
+	return scaleLuminance(c, func(l float64) float64 { return l / (1 + l) })
+}
+
+// ExtendedReinhardToneMapper is Reinhard with a white point: a luminance of White (or more) is mapped to 1
+type ExtendedReinhardToneMapper struct {
+	White float64
+}
+
+func (tm ExtendedReinhardToneMapper) ToneMap(c Color) Color {
+	w2 := tm.White * tm.White
+	return scaleLuminance(c, func(l float64) float64 { return l * (1 + l/w2) / (1 + l) })
+}
+
+// ACESToneMapper is the filmic curve of the Academy Color Encoding System (fit by Krzysztof Narkowicz) applied to
+// each component (contrasty, desaturates very bright colors)
+type ACESToneMapper struct{}
+
+func (tm ACESToneMapper) ToneMap(c Color) Color {
+	aces := func(x float64) float64 {
+		x = math.Max(x, 0)
+		return clamp01(x * (2.51*x + 0.03) / (x*(2.43*x+0.59) + 0.14))
+	}
+	return Color{R: aces(c.R), G: aces(c.G), B: aces(c.B)}
+}
+
+// HableToneMapper is the filmic curve designed by John Hable for Uncharted 2 applied to each component. White is the
+// linear value mapped to 1
+type HableToneMapper struct {
+	White float64
+}
+
+// hable is the curve itself (shoulder, linear section and toe)
+func hable(x float64) float64 {
+	const a, b, c, d, e, f = 0.15, 0.50, 0.10, 0.20, 0.02, 0.30
+	return (x*(a*x+c*b)+d*e)/(x*(a*x+b)+d*f) - e/f
+}
+
+func (tm HableToneMapper) ToneMap(c Color) Color {
+	// the curve is designed to be used with an exposure bias of 2
+	const exposureBias = 2.0
+	whiteScale := 1.0 / hable(tm.White*exposureBias)
+	curve := func(x float64) float64 {
+		return clamp01(hable(math.Max(x, 0)*exposureBias) * whiteScale)
+	}
+	return Color{R: curve(c.R), G: curve(c.G), B: curve(c.B)}
+}
+
+// scaleLuminance maps the luminance of the color with f (keeping the ratio between the components) and clamps
+func scaleLuminance(c Color, f func(l float64) float64) Color {
+	l := c.Luminance()
+	if l <= 0 {
+		return Black
+	}
+	return ClampToneMapper{}.ToneMap(c.Scale(f(l) / l))
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 || math.IsNaN(v) {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,76 @@
+package tracer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestToneMapper(t *testing.T) {
+	gray := func(v float64) Color { return Color{v, v, v} }
+
+	var tests = []struct {
+		spec     string
+		white    float64
+		input    Color
+		expected Color
+	}{
+		{"clamp", 0, Color{0.25, 1.5, -1}, Color{0.25, 1, 0}},
+		{"reinhard", 0, gray(1), gray(0.5)},
+		{"reinhard", 0, gray(3), gray(0.75)},
+		{"reinhard-extended", 2, gray(2), gray(1)},
+		{"reinhard-extended", 0, gray(DefaultReinhardWhite), gray(1)},
+		{"aces", 0, Black, Black},
+		{"aces", 0, gray(1000), gray(1)},
+		{"hable", 0, gray(DefaultHableWhite), gray(1)},
+		{"hable", 5, gray(5), gray(1)},
+		{"hable", 5, Black, Black},
+	}
+
+	for idx, test := range tests {
+		tm, err := ParseToneMapper(test.spec, test.white)
+		if err != nil {
+			t.Fatalf("unexpected error %v [test %v]", err, idx)
+		}
+		c := tm.ToneMap(test.input)
+		if math.Abs(c.R-test.expected.R) > 1e-3 || math.Abs(c.G-test.expected.G) > 1e-3 || math.Abs(c.B-test.expected.B) > 1e-3 {
+			t.Errorf("%v expected got %v instead [test %v]", test.expected, c, idx)
+		}
+	}
+
+	// every operator maps to [0,1] and is monotonic (brighter in => brighter out)
+	for _, name := range ToneMapperNames {
+		tm, _ := ParseToneMapper(name, 0)
+		previous := -1.0
+		for v := 0.0; v < 100; v += 0.1 {
+			c := tm.ToneMap(gray(v))
+			if c.R < 0 || c.R > 1 || c.R < previous {
+				t.Errorf("%v: %v maps to %v (previous %v)", name, v, c.R, previous)
+				break
+			}
+			previous = c.R
+		}
+		if c := tm.ToneMap(Color{100, 0.5, 0}); c.R < 0 || c.R > 1 || c.G < 0 || c.G > 1 || c.B < 0 || c.B > 1 {
+			t.Errorf("%v: out of range %v", name, c)
+		}
+	}
+
+	if _, err := ParseToneMapper("filmic", 0); err == nil {
+		t.Errorf("error expected for unknown tone mapper")
+	}
+}
+
+func TestExposureScale(t *testing.T) {
+	var tests = []struct {
+		stops, expected float64
+	}{
+		{0, 1},
+		{1, 2},
+		{-2, 0.25},
+	}
+
+	for idx, test := range tests {
+		if s := ExposureScale(test.stops); s != test.expected {
+			t.Errorf("%v expected got %v instead [test %v]", test.expected, s, idx)
+		}
+	}
+}
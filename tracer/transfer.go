@@ -0,0 +1,148 @@
+package tracer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/***********************
+ * OutputTransform
+ ************************/
+// OutputTransform encodes the linear colors in [0,1] (after tone mapping) into the values stored in the pixels
+// (the opto-electronic transfer function). It is applied to the live preview and the saved (8 bits) image.
+type OutputTransform interface {
+	Encode(c Color) Color
+}
+
+// SRGBTransform is the exact sRGB transfer function (linear segment near black and 2.4 power curve) which is what
+// most viewers expect (the default)
+type SRGBTransform struct{}
+
+func (t SRGBTransform) Encode(c Color) Color {
+	return LinearToSRGB(c)
+}
+
+// GammaTransform is a pure power curve (1/Gamma) (for example 2.2 or 2.0 which is what the book uses)
+type GammaTransform struct {
+	Gamma float64
+}
+
+func (t GammaTransform) Encode(c Color) Color {
+	g := 1.0 / t.Gamma
+	return Color{R: math.Pow(c.R, g), G: math.Pow(c.G, g), B: math.Pow(c.B, g)}
+}
+
+// LinearTransform stores the linear values as is (for tools which expect linear data)
+type LinearTransform struct{}
+
+func (t LinearTransform) Encode(c Color) Color {
+	return c
+}
+
+// ParseOutputTransform parses the output transform: srgb, linear, gamma (2.2) or gamma:g
+func ParseOutputTransform(spec string) (OutputTransform, error) {
+	switch spec {
+	case "srgb":
+		return SRGBTransform{}, nil
+	case "linear":
+		return LinearTransform{}, nil
+	case "gamma":
+		return GammaTransform{Gamma: 2.2}, nil
+	}
+
+	if strings.HasPrefix(spec, "gamma:") {
+		g, err := strconv.ParseFloat(strings.TrimPrefix(spec, "gamma:"), 64)
+		if err != nil || g <= 0 {
+			return nil, fmt.Errorf("invalid output transform [%v]: gamma must be a positive number", spec)
+		}
+		return GammaTransform{Gamma: g}, nil
+	}
+
+	return nil, fmt.Errorf("unknown output transform [%v] (must be one of srgb, linear, gamma or gamma:g)", spec)
+}
+
+// LinearToSRGB encodes a linear color with the sRGB transfer function
+func LinearToSRGB(c Color) Color {
+	encode := func(v float64) float64 {
+		if v <= 0.0031308 {
+			return 12.92 * v
+		}
+		return 1.055*math.Pow(v, 1.0/2.4) - 0.055
+	}
+	return Color{R: encode(c.R), G: encode(c.G), B: encode(c.B)}
+}
+
+// SRGBToLinear decodes a color encoded with the sRGB transfer function (like the colors of png and jpeg images)
+func SRGBToLinear(c Color) Color {
+	decode := func(v float64) float64 {
+		if v <= 0.04045 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	return Color{R: decode(c.R), G: decode(c.G), B: decode(c.B)}
+}
+
+// EncodePNG writes the image in png format and embeds the chunks describing how its values are encoded so that
+// viewers and compositing tools interpret them the same way: sRGB (along with the equivalent gAMA and cHRM) for
+// SRGBTransform, gAMA for GammaTransform and LinearTransform (no chunk for any other transform)
+func EncodePNG(w io.Writer, img image.Image, transform OutputTransform) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+
+	var chunks bytes.Buffer
+	switch t := transform.(type) {
+	case SRGBTransform:
+		// rendering intent: perceptual
+		writePNGChunk(&chunks, "sRGB", []byte{0})
+		writePNGChunk(&chunks, "gAMA", pngUint32(45455))
+		// white point and red, green, blue primaries (x, y) * 100000
+		writePNGChunk(&chunks, "cHRM", pngUint32(31270, 32900, 64000, 33000, 30000, 60000, 15000, 6000))
+	case GammaTransform:
+		writePNGChunk(&chunks, "gAMA", pngUint32(uint32(math.Round(100000/t.Gamma))))
+	case LinearTransform:
+		writePNGChunk(&chunks, "gAMA", pngUint32(100000))
+	}
+
+	// the chunks must come before the image data: right after the signature (8 bytes) and IHDR (8 + 13 + 4 bytes)
+	const ihdrEnd = 8 + 8 + 13 + 4
+	data := buf.Bytes()
+	if _, err := w.Write(data[:ihdrEnd]); err != nil {
+		return err
+	}
+	if _, err := w.Write(chunks.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.Write(data[ihdrEnd:])
+	return err
+}
+
+// writePNGChunk writes a png chunk (length, type, data and crc of type and data)
+func writePNGChunk(w *bytes.Buffer, typ string, data []byte) {
+	binary.Write(w, binary.BigEndian, uint32(len(data)))
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data)
+	w.WriteString(typ)
+	w.Write(data)
+	binary.Write(w, binary.BigEndian, crc.Sum32())
+}
+
+// pngUint32 encodes the values as png does (big endian)
+func pngUint32(values ...uint32) []byte {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(data[i*4:], v)
+	}
+	return data
+}
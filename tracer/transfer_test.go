@@ -0,0 +1,128 @@
+package tracer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"math"
+	"testing"
+)
+
+func TestSRGB(t *testing.T) {
+	var tests = []struct {
+		linear, encoded float64
+	}{
+		{0, 0},
+		{1, 1},
+		{0.001, 0.01292},
+		{0.5, 0.735357},
+		{0.214041, 0.5},
+	}
+
+	for idx, test := range tests {
+		if c := LinearToSRGB(Color{test.linear, test.linear, test.linear}); math.Abs(c.R-test.encoded) > 1e-5 {
+			t.Errorf("%v expected got %v instead [test %v]", test.encoded, c.R, idx)
+		}
+		if c := SRGBToLinear(Color{test.encoded, test.encoded, test.encoded}); math.Abs(c.G-test.linear) > 1e-5 {
+			t.Errorf("%v expected got %v instead [test %v]", test.linear, c.G, idx)
+		}
+	}
+
+	// round trip
+	for v := 0.0; v <= 1; v += 0.01 {
+		if c := SRGBToLinear(LinearToSRGB(Color{v, v, v})); math.Abs(c.B-v) > 1e-9 {
+			t.Errorf("%v expected got %v instead", v, c.B)
+		}
+	}
+}
+
+func TestParseOutputTransform(t *testing.T) {
+	var tests = []struct {
+		spec     string
+		expected OutputTransform
+	}{
+		{"srgb", SRGBTransform{}},
+		{"linear", LinearTransform{}},
+		{"gamma", GammaTransform{2.2}},
+		{"gamma:2", GammaTransform{2}},
+	}
+
+	for idx, test := range tests {
+		transform, err := ParseOutputTransform(test.spec)
+		if err != nil || transform != test.expected {
+			t.Errorf("%v expected got %v (%v) instead [test %v]", test.expected, transform, err, idx)
+		}
+	}
+
+	for _, spec := range []string{"rec709", "gamma:", "gamma:-1", "gamma:abc"} {
+		if _, err := ParseOutputTransform(spec); err == nil {
+			t.Errorf("error expected for %v", spec)
+		}
+	}
+
+	if c := (GammaTransform{2}).Encode(Color{0.25, 1, 0}); c != (Color{0.5, 1, 0}) {
+		t.Errorf("unexpected gamma encoding %v", c)
+	}
+}
+
+// pngChunks returns the types of the chunks of a png file with the data of each one
+func pngChunks(data []byte) ([]string, map[string][]byte) {
+	var types []string
+	chunks := map[string][]byte{}
+	for rest := data[8:]; len(rest) >= 12; {
+		length := int(binary.BigEndian.Uint32(rest))
+		typ := string(rest[4:8])
+		types = append(types, typ)
+		chunks[typ] = rest[8 : 8+length]
+		rest = rest[12+length:]
+	}
+	return types, chunks
+}
+
+func TestEncodePNG(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 3))
+	img.Pix[0], img.Pix[3] = 200, 255
+
+	var tests = []struct {
+		transform OutputTransform
+		chunks    []string
+		gamma     uint32
+	}{
+		{SRGBTransform{}, []string{"IHDR", "sRGB", "gAMA", "cHRM", "IDAT", "IEND"}, 45455},
+		{GammaTransform{2.2}, []string{"IHDR", "gAMA", "IDAT", "IEND"}, 45455},
+		{GammaTransform{2}, []string{"IHDR", "gAMA", "IDAT", "IEND"}, 50000},
+		{LinearTransform{}, []string{"IHDR", "gAMA", "IDAT", "IEND"}, 100000},
+	}
+
+	for idx, test := range tests {
+		var buf bytes.Buffer
+		if err := EncodePNG(&buf, img, test.transform); err != nil {
+			t.Fatal(err)
+		}
+
+		// still a valid png (the crc of each chunk is checked)
+		decoded, err := png.Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%v [test %v]", err, idx)
+		}
+		if r, _, _, _ := decoded.At(0, 0).RGBA(); r>>8 != 200 {
+			t.Errorf("unexpected pixel value %v [test %v]", r>>8, idx)
+		}
+
+		types, chunks := pngChunks(buf.Bytes())
+		if len(types) != len(test.chunks) {
+			t.Errorf("%v expected got %v instead [test %v]", test.chunks, types, idx)
+			continue
+		}
+		for i := range types {
+			if types[i] != test.chunks[i] {
+				t.Errorf("%v expected got %v instead [test %v]", test.chunks, types, idx)
+				break
+			}
+		}
+		if gamma := binary.BigEndian.Uint32(chunks["gAMA"]); gamma != test.gamma {
+			t.Errorf("%v expected got %v instead [test %v]", test.gamma, gamma, idx)
+		}
+	}
+}
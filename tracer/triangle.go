@@ -0,0 +1,235 @@
+package tracer
+
+import (
+	"math"
+)
+
+// UV defines surface (texture) coordinates
+type UV struct {
+	U, V float64
+}
+
+/***********************
+ * Triangle
+ ************************/
+// Triangle is a single triangle defined by its 3 vertices. The (geometric) normal follows the right hand rule
+// (counter clockwise vertices are facing the viewer) which matters for dielectric materials.
+//	normals are optional per vertex normals (interpolated for smooth shading, geometric normal used if nil)
+//	uvs are optional per vertex surface coordinates (barycentric coordinates used if nil)
+type Triangle struct {
+	vertices [3]Point3
+	normals  *[3]Vec3
+	uvs      *[3]UV
+	material Material
+}
+
+// NewTriangle creates a flat triangle (no per vertex normals or uvs)
+func NewTriangle(p0, p1, p2 Point3, material Material) Triangle {
+	return Triangle{vertices: [3]Point3{p0, p1, p2}, material: material}
+}
+
+// NewSmoothTriangle creates a triangle with per vertex normals and uvs
+func NewSmoothTriangle(vertices [3]Point3, normals [3]Vec3, uvs [3]UV, material Material) Triangle {
+	return Triangle{vertices: vertices, normals: &normals, uvs: &uvs, material: material}
+}
+
+// Hit implements the Hitable interface for a Triangle
+func (tri Triangle) Hit(r *Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	hit, t, b1, b2 := intersectTriangle(r, tMin, tMax, tri.vertices[0], tri.vertices[1], tri.vertices[2])
+	if !hit {
+		return false, nil
+	}
+
+	hr := &HitRecord{T: t, P: r.PointAt(t), Material: tri.material}
+
+	if tri.normals != nil {
+		hr.Normal = interpolateNormal(b1, b2, tri.normals[0], tri.normals[1], tri.normals[2])
+	} else {
+		hr.Normal = Cross(tri.vertices[1].Sub(tri.vertices[0]), tri.vertices[2].Sub(tri.vertices[0])).Unit()
+	}
+
+	if tri.uvs != nil {
+		hr.U, hr.V = interpolateUV(b1, b2, tri.uvs[0], tri.uvs[1], tri.uvs[2])
+	} else {
+		hr.U, hr.V = b1, b2
+	}
+
+	return true, hr
+}
+
+// BoundingBox implements the Hitable interface for a Triangle
+func (tri Triangle) BoundingBox() (bool, *AABB) {
+	return true, triangleBoundingBox(tri.vertices[0], tri.vertices[1], tri.vertices[2])
+}
+
+/***********************
+ * Mesh
+ ************************/
+// Mesh is an indexed triangle mesh: the triangles share the vertex buffers (vertices, normals, uvs) and are
+// defined by 3 indices each. A Mesh can be used anywhere a Hitable goes (it uses its own bounding volume hierarchy).
+// Several meshes can share the same vertex buffers (ex: one mesh per material)
+// When the mesh has per vertex colors (ex: scanned data), the material is a Lambertian whose albedo is the
+// interpolated color (see vertexColorMaterial)
+type Mesh struct {
+	vertices []Point3
+	normals  []Vec3  // optional per vertex normals (same length as vertices)
+	uvs      []UV    // optional per vertex uvs (same length as vertices)
+	colors   []Color // optional per vertex colors (same length as vertices)
+	indices  []int   // 3 indices per triangle
+	material Material
+	bvh      Hitable
+}
+
+// NewMesh creates a mesh (normals and uvs can be nil) and builds its bounding volume hierarchy
+func NewMesh(vertices []Point3, normals []Vec3, uvs []UV, indices []int, material Material) *Mesh {
+	mesh := &Mesh{vertices: vertices, normals: normals, uvs: uvs, indices: indices, material: material}
+	mesh.bvh, _ = BuildBVH(mesh.Triangles(), SplitSAH)
+	return mesh
+}
+
+// NewColoredMesh creates a mesh with per vertex colors (normals can be nil) and builds its bounding volume hierarchy
+func NewColoredMesh(vertices []Point3, normals []Vec3, colors []Color, indices []int) *Mesh {
+	mesh := &Mesh{vertices: vertices, normals: normals, colors: colors, indices: indices, material: vertexColorMaterial{}}
+	mesh.bvh, _ = BuildBVH(mesh.Triangles(), SplitSAH)
+	return mesh
+}
+
+// TriangleCount returns the number of triangles in the mesh
+func (mesh *Mesh) TriangleCount() int {
+	return len(mesh.indices) / 3
+}
+
+// Triangles returns the triangles of the mesh as individual hitables (which still share the mesh buffers)
+func (mesh *Mesh) Triangles() HitableList {
+	triangles := make(HitableList, mesh.TriangleCount())
+	for i := range triangles {
+		triangles[i] = meshTriangle{mesh, i * 3}
+	}
+	return triangles
+}
+
+// Hit implements the Hitable interface for a Mesh
+func (mesh *Mesh) Hit(r *Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	return mesh.bvh.Hit(r, tMin, tMax)
+}
+
+// BoundingBox implements the Hitable interface for a Mesh
+func (mesh *Mesh) BoundingBox() (bool, *AABB) {
+	return mesh.bvh.BoundingBox()
+}
+
+// meshTriangle is a triangle in a mesh (offset is the index of its first vertex index)
+type meshTriangle struct {
+	mesh   *Mesh
+	offset int
+}
+
+func (mt meshTriangle) Hit(r *Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	mesh := mt.mesh
+	i0, i1, i2 := mesh.indices[mt.offset], mesh.indices[mt.offset+1], mesh.indices[mt.offset+2]
+	p0, p1, p2 := mesh.vertices[i0], mesh.vertices[i1], mesh.vertices[i2]
+
+	hit, t, b1, b2 := intersectTriangle(r, tMin, tMax, p0, p1, p2)
+	if !hit {
+		return false, nil
+	}
+
+	hr := &HitRecord{T: t, P: r.PointAt(t), Material: mesh.material}
+
+	if mesh.normals != nil {
+		hr.Normal = interpolateNormal(b1, b2, mesh.normals[i0], mesh.normals[i1], mesh.normals[i2])
+	} else {
+		hr.Normal = Cross(p1.Sub(p0), p2.Sub(p0)).Unit()
+	}
+
+	if mesh.uvs != nil {
+		hr.U, hr.V = interpolateUV(b1, b2, mesh.uvs[i0], mesh.uvs[i1], mesh.uvs[i2])
+	} else {
+		hr.U, hr.V = b1, b2
+	}
+
+	if mesh.colors != nil {
+		b0 := 1 - b1 - b2
+		hr.color = mesh.colors[i0].Scale(b0).Add(mesh.colors[i1].Scale(b1)).Add(mesh.colors[i2].Scale(b2))
+	}
+
+	return true, hr
+}
+
+func (mt meshTriangle) BoundingBox() (bool, *AABB) {
+	mesh := mt.mesh
+	return true, triangleBoundingBox(mesh.vertices[mesh.indices[mt.offset]], mesh.vertices[mesh.indices[mt.offset+1]], mesh.vertices[mesh.indices[mt.offset+2]])
+}
+
+/***********************
+ * Utilities functions
+ ************************/
+// intersectTriangle implements the Möller–Trumbore algorithm (both sides of the triangle can be hit)
+//	returns the t of the hit and the barycentric coordinates b1 (for p1) and b2 (for p2)
+func intersectTriangle(r *Ray, tMin float64, tMax float64, p0, p1, p2 Point3) (bool, float64, float64, float64) {
+	const epsilon = 1e-12
+
+	e1 := p1.Sub(p0)
+	e2 := p2.Sub(p0)
+	pvec := Cross(r.Direction, e2)
+	det := Dot(e1, pvec)
+
+	// ray parallel to the triangle
+	if math.Abs(det) < epsilon {
+		return false, 0, 0, 0
+	}
+
+	invDet := 1.0 / det
+	tvec := r.Origin.Sub(p0)
+
+	b1 := Dot(tvec, pvec) * invDet
+	if b1 < 0 || b1 > 1 {
+		return false, 0, 0, 0
+	}
+
+	qvec := Cross(tvec, e1)
+	b2 := Dot(r.Direction, qvec) * invDet
+	if b2 < 0 || b1+b2 > 1 {
+		return false, 0, 0, 0
+	}
+
+	t := Dot(e2, qvec) * invDet
+	if t < tMax && t > tMin {
+		return true, t, b1, b2
+	}
+
+	return false, 0, 0, 0
+}
+
+// interpolateNormal interpolates the 3 normals using barycentric coordinates
+func interpolateNormal(b1, b2 float64, n0, n1, n2 Vec3) Vec3 {
+	return n0.Scale(1 - b1 - b2).Add(n1.Scale(b1)).Add(n2.Scale(b2)).Unit()
+}
+
+// interpolateUV interpolates the 3 uvs using barycentric coordinates
+func interpolateUV(b1, b2 float64, uv0, uv1, uv2 UV) (float64, float64) {
+	b0 := 1 - b1 - b2
+	return b0*uv0.U + b1*uv1.U + b2*uv2.U, b0*uv0.V + b1*uv1.V + b2*uv2.V
+}
+
+// triangleBoundingBox returns the box containing the 3 points (with some thickness for axis aligned triangles)
+func triangleBoundingBox(p0, p1, p2 Point3) *AABB {
+	box := &AABB{p0, p0}
+	box = SurroundingBox(box, &AABB{p1, p1})
+	box = SurroundingBox(box, &AABB{p2, p2})
+
+	if box.Max.X-box.Min.X < rectThickness {
+		box.Min.X -= rectThickness
+		box.Max.X += rectThickness
+	}
+	if box.Max.Y-box.Min.Y < rectThickness {
+		box.Min.Y -= rectThickness
+		box.Max.Y += rectThickness
+	}
+	if box.Max.Z-box.Min.Z < rectThickness {
+		box.Min.Z -= rectThickness
+		box.Max.Z += rectThickness
+	}
+
+	return box
+}
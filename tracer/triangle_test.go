@@ -0,0 +1,63 @@
+package tracer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTriangle_Hit(t *testing.T) {
+	tri := NewTriangle(Point3{0, 0, 0}, Point3{1, 0, 0}, Point3{0, 1, 0}, Lambertian{White})
+
+	var tests = []struct {
+		r        Ray
+		expected bool
+		u, v     float64
+	}{
+		{Ray{Origin: Point3{0.25, 0.5, 1}, Direction: Vec3{Z: -1}}, true, 0.25, 0.5},
+		{Ray{Origin: Point3{0.25, 0.5, -1}, Direction: Vec3{Z: 1}}, true, 0.25, 0.5},
+		{Ray{Origin: Point3{0.75, 0.5, 1}, Direction: Vec3{Z: -1}}, false, 0, 0},
+		{Ray{Origin: Point3{0.25, 0.5, 1}, Direction: Vec3{X: 1}}, false, 0, 0},
+	}
+
+	for idx, test := range tests {
+		hit, hr := tri.Hit(&test.r, 0.001, math.MaxFloat64)
+		if hit != test.expected {
+			t.Errorf("expected %v got %v instead [test %v]", test.expected, hit, idx)
+			continue
+		}
+		if hit {
+			if !floatEquals(hr.U, test.u) || !floatEquals(hr.V, test.v) || !floatEquals(hr.T, 1.0) {
+				t.Errorf("unexpected hit u=%v v=%v t=%v [test %v]", hr.U, hr.V, hr.T, idx)
+			}
+			if hr.Normal != (Vec3{Z: 1}) {
+				t.Errorf("unexpected normal %v [test %v]", hr.Normal, idx)
+			}
+		}
+	}
+}
+
+func TestMesh_Hit(t *testing.T) {
+	// unit square made of 2 triangles sharing 2 vertices, with normals and uvs
+	vertices := []Point3{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}}
+	normals := []Vec3{{Z: 1}, {Z: 1}, {Z: 1}, {Z: 1}}
+	uvs := []UV{{0, 0}, {1, 0}, {1, 1}, {0, 1}}
+	mesh := NewMesh(vertices, normals, uvs, []int{0, 1, 2, 0, 2, 3}, Lambertian{White})
+
+	if mesh.TriangleCount() != 2 {
+		t.Fatalf("expected 2 triangles got %v", mesh.TriangleCount())
+	}
+
+	for _, p := range []Point3{{0.8, 0.3, 1}, {0.3, 0.8, 1}} {
+		hit, hr := mesh.Hit(&Ray{Origin: p, Direction: Vec3{Z: -1}}, 0.001, math.MaxFloat64)
+		if !hit {
+			t.Fatalf("expected hit at %v", p)
+		}
+		if !floatEquals(hr.U, p.X) || !floatEquals(hr.V, p.Y) {
+			t.Errorf("expected uv %v/%v got %v/%v instead", p.X, p.Y, hr.U, hr.V)
+		}
+	}
+
+	if hit, _ := mesh.Hit(&Ray{Origin: Point3{1.5, 0.5, 1}, Direction: Vec3{Z: -1}}, 0.001, math.MaxFloat64); hit {
+		t.Errorf("unexpected hit outside of the mesh")
+	}
+}